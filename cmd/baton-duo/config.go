@@ -12,11 +12,24 @@ import (
 type config struct {
 	cli.BaseConfig `mapstructure:",squash"` // Puts the base config options in the same place as the connector options
 
-	IntegrationKey string `mapstructure:"integration-key"`
-	SecretKey      string `mapstructure:"secret-key"`
-	ApiHostname    string `mapstructure:"api-hostname"`
+	IntegrationKey       string `mapstructure:"integration-key"`
+	SecretKey            string `mapstructure:"secret-key"`
+	ApiHostname          string `mapstructure:"api-hostname"`
+	AdminFallbackRole    string `mapstructure:"admin-fallback-role"`
+	TelephonyUsageDays   int    `mapstructure:"telephony-usage-days"`
+	ChildAccounts        bool   `mapstructure:"child-accounts"`
+	BypassCodeCount      int    `mapstructure:"bypass-code-count"`
+	BypassCodeReuseCount int    `mapstructure:"bypass-code-reuse-count"`
+	BypassCodeValidSecs  int    `mapstructure:"bypass-code-valid-secs"`
 }
 
+const (
+	// Duo keeps logs for 180 days.
+	maxLogRetentionDays = 180
+	// Duo issues at most 10 bypass codes per request.
+	maxBypassCodeCount = 10
+)
+
 // validateConfig is run after the configuration is loaded, and should return an error if it isn't valid.
 func validateConfig(ctx context.Context, cfg *config) error {
 	if cfg.IntegrationKey == "" {
@@ -31,6 +44,26 @@ func validateConfig(ctx context.Context, cfg *config) error {
 		return fmt.Errorf("api host name is missing")
 	}
 
+	if cfg.AdminFallbackRole == "" {
+		return fmt.Errorf("admin fallback role is missing")
+	}
+
+	if cfg.TelephonyUsageDays < 0 || cfg.TelephonyUsageDays > maxLogRetentionDays {
+		return fmt.Errorf("telephony usage days must be between 0 and %d", maxLogRetentionDays)
+	}
+
+	if cfg.BypassCodeCount < 1 || cfg.BypassCodeCount > maxBypassCodeCount {
+		return fmt.Errorf("bypass code count must be between 1 and %d", maxBypassCodeCount)
+	}
+
+	if cfg.BypassCodeReuseCount < 0 {
+		return fmt.Errorf("bypass code reuse count must be 0 or more")
+	}
+
+	if cfg.BypassCodeValidSecs < 0 {
+		return fmt.Errorf("bypass code valid secs must be 0 or more")
+	}
+
 	return nil
 }
 
@@ -39,4 +72,10 @@ func cmdFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().String("integration-key", "", "Duo integration key needed to complete the setup to connect to the Duo API. ($BATON_INTEGRATION_KEY)")
 	cmd.PersistentFlags().String("secret-key", "", "Duo secret key needed to complete the setup to connect to the Duo API. ($BATON_SECRET_KEY)")
 	cmd.PersistentFlags().String("api-hostname", "", "Duo api hostname key needed to complete the setup to connect to the Duo API. ($BATON_API_HOSTNAME)")
+	cmd.PersistentFlags().String("admin-fallback-role", "Read-only", "Duo role given to an admin when their role is revoked. ($BATON_ADMIN_FALLBACK_ROLE)")
+	cmd.PersistentFlags().Bool("child-accounts", false, "Use the credentials of an Accounts API integration to sync the users, groups and admins of every child account. ($BATON_CHILD_ACCOUNTS)")
+	cmd.PersistentFlags().Int("telephony-usage-days", 0, "Report telephony credits used by each phone over this many days, 0 disables it. ($BATON_TELEPHONY_USAGE_DAYS)")
+	cmd.PersistentFlags().Int("bypass-code-count", 1, "Number of bypass codes issued when rotating a user's credentials, up to 10. ($BATON_BYPASS_CODE_COUNT)")
+	cmd.PersistentFlags().Int("bypass-code-reuse-count", 1, "Number of times each issued bypass code can be used, 0 for unlimited. ($BATON_BYPASS_CODE_REUSE_COUNT)")
+	cmd.PersistentFlags().Int("bypass-code-valid-secs", 3600, "Number of seconds issued bypass codes stay valid, 0 for no expiry. ($BATON_BYPASS_CODE_VALID_SECS)")
 }
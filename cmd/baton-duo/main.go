@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/conductorone/baton-duo/pkg/connector"
+	"github.com/conductorone/baton-duo/pkg/duo"
 	"github.com/conductorone/baton-sdk/pkg/cli"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/types"
@@ -38,7 +39,20 @@ func main() {
 func getConnector(ctx context.Context, cfg *config) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	cb, err := connector.New(ctx, cfg.IntegrationKey, cfg.SecretKey, cfg.ApiHostname)
+	cb, err := connector.New(
+		ctx,
+		cfg.IntegrationKey,
+		cfg.SecretKey,
+		cfg.ApiHostname,
+		cfg.AdminFallbackRole,
+		cfg.TelephonyUsageDays,
+		cfg.ChildAccounts,
+		duo.BypassCodeParams{
+			Count:      cfg.BypassCodeCount,
+			ReuseCount: cfg.BypassCodeReuseCount,
+			ValidSecs:  cfg.BypassCodeValidSecs,
+		},
+	)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
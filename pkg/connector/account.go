@@ -8,13 +8,47 @@ import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+const (
+	disabledEntitlement = "disabled"
+	bypassEntitlement   = "bypass"
+
+	userStatusActive   = "active"
+	userStatusBypass   = "bypass"
+	userStatusDisabled = "disabled"
+
+	// previousStatusKey holds the status a user had before being disabled, on the
+	// grant returned when disabling them.
+	previousStatusKey = "previous_status"
+)
+
+// groupStatusEntitlements are the account entitlements that set a group's status.
+var groupStatusEntitlements = map[string]string{
+	disabledEntitlement: groupStatusDisabled,
+	bypassEntitlement:   groupStatusBypass,
+}
+
 type accountResourceType struct {
 	resourceType   *v2.ResourceType
-	client         *duo.Client
+	accounts       *accountClients
 	integrationKey string
+
+	// Listing the account starts a fresh sync, so it drops what an earlier sync
+	// left in the caches. A resumed sync may start after the account with empty
+	// caches, so the caches are only a shortcut and every reader falls back to
+	// Duo on a miss.
+	caches []syncCache
+
+	// disabledUsers holds the disabled users found while listing users, by
+	// account, for the accounts whose users were all listed by this process.
+	disabledUsers *grantCache
 }
 
 func (o *accountResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -29,6 +63,13 @@ func accountResource(ctx context.Context, account duo.Account, integrationKey st
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeGroup.Id},
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeAdmin.Id},
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeRole.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypePhone.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeToken.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeWebAuthnCredential.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeBypassCode.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeApplication.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeAdministrativeUnit.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypePolicy.Id},
 		),
 	}
 	ret, err := rs.NewResource(
@@ -44,10 +85,39 @@ func accountResource(ctx context.Context, account duo.Account, integrationKey st
 	return ret, nil
 }
 
+// Create a new connector resource for a child account of an Accounts API
+// integration. Only users, groups and admins are synced for child accounts.
+func childAccountResource(ctx context.Context, account *duo.ChildAccount) (*v2.Resource, error) {
+	ret, err := rs.NewResource(
+		account.Name,
+		resourceTypeAccount,
+		account.AccountID,
+		rs.WithDescription(describe("api_hostname", account.APIHostname)),
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeUser.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeGroup.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeAdmin.Id},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
 func (o *accountResourceType) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	account, err := o.client.GetAccount(ctx)
+	for _, cache := range o.caches {
+		cache.reset()
+	}
+
+	if o.accounts.childAccounts {
+		return o.listChildAccounts(ctx)
+	}
+
+	account, annos, err := o.accounts.client.GetAccount(ctx)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("duo-connector: failed to list an account: %w", err)
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list an account")
 	}
 
 	var rv []*v2.Resource
@@ -57,21 +127,366 @@ func (o *accountResourceType) List(ctx context.Context, _ *v2.ResourceId, _ *pag
 	}
 	rv = append(rv, ar)
 
+	return rv, "", annos, nil
+}
+
+func (o *accountResourceType) listChildAccounts(ctx context.Context) ([]*v2.Resource, string, annotations.Annotations, error) {
+	accounts, annos, err := o.accounts.client.GetChildAccounts(ctx)
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list child accounts")
+	}
+	o.accounts.set(accounts)
+
+	var rv []*v2.Resource
+	for _, account := range accounts {
+		accountCopy := account
+		ar, err := childAccountResource(ctx, &accountCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, ar)
+	}
+
+	return rv, "", annos, nil
+}
+
+// Entitlements returns the "disabled" entitlement, which models a user or group
+// being cut off in Duo while it is kept for audit, and the "bypass" entitlement,
+// which lets a group's members skip two-factor authentication.
+func (o *accountResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeUser, resourceTypeGroup),
+		ent.WithDescription(fmt.Sprintf("Disabled user or group in %s Duo account", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Account %s", resource.DisplayName, disabledEntitlement)),
+	}
+
+	en := ent.NewAssignmentEntitlement(resource, disabledEntitlement, assignmentOptions...)
+	rv = append(rv, en)
+
+	bypassOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeGroup),
+		ent.WithDescription(fmt.Sprintf("Group whose members skip two-factor authentication in %s Duo account", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Account %s", resource.DisplayName, bypassEntitlement)),
+	}
+
+	en = ent.NewAssignmentEntitlement(resource, bypassEntitlement, bypassOptions...)
+	rv = append(rv, en)
+
 	return rv, "", nil, nil
 }
 
-func (o *accountResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+// Grants returns the disabled users, then the disabled and bypass groups. The
+// users found disabled while listing the account's users are used, so users
+// aren't paged through a second time. When this process didn't list all of
+// them, e.g. in a resumed sync, the account's users are paged from Duo instead.
+// Groups are always paged from Duo.
+func (o *accountResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeUser.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if bag.ResourceTypeID() == resourceTypeGroup.Id {
+		return o.groupStatusGrants(ctx, resource, bag)
+	}
+
+	rv, annos, err := o.disabledUserGrants(ctx, resource, bag)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	// The users are done once their page token is gone, so the groups come next.
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeGroup.Id})
+	}
+
+	pageToken, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, annos, nil
 }
 
-func (o *accountResourceType) Grants(ctx context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+// disabledUserGrants returns a page of disabled users, advancing the bag to the
+// next page, or popping it after the last one.
+func (o *accountResourceType) disabledUserGrants(ctx context.Context, resource *v2.Resource, bag *pagination.Bag) ([]*v2.Grant, annotations.Annotations, error) {
+	if bag.PageToken() == "" {
+		if disabledUsers, ok := o.disabledUsers.get(resource.Id.Resource); ok {
+			var rv []*v2.Grant
+			for _, principal := range disabledUsers {
+				rv = append(rv, grant.NewGrant(resource, disabledEntitlement, principal))
+			}
+			bag.Pop()
+
+			return rv, nil, nil
+		}
+	}
+
+	client, annos, err := o.accounts.forAccount(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	users, offset, listAnnos, err := client.GetUsers(ctx, bag.PageToken())
+	annos = append(annos, listAnnos...)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to list users")
+	}
+
+	if err := bag.Next(offset); err != nil {
+		return nil, annos, err
+	}
+
+	var rv []*v2.Grant
+	for _, user := range users {
+		if user.Status != userStatusDisabled {
+			continue
+		}
+
+		principal, err := rs.NewResourceID(resourceTypeUser, user.UserID)
+		if err != nil {
+			return nil, annos, err
+		}
+		rv = append(rv, grant.NewGrant(resource, disabledEntitlement, principal))
+	}
+
+	return rv, annos, nil
+}
+
+// groupStatusGrants returns a page of the groups that are disabled or in bypass.
+func (o *accountResourceType) groupStatusGrants(ctx context.Context, resource *v2.Resource, bag *pagination.Bag) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var pageToken string
+
+	client, annos, err := o.accounts.forAccount(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	groups, offset, listAnnos, err := client.GetGroups(ctx, bag.PageToken())
+	annos = append(annos, listAnnos...)
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list groups")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Grant
+	for _, group := range groups {
+		for slug, groupStatus := range groupStatusEntitlements {
+			if group.Status != groupStatus {
+				continue
+			}
+
+			principal, err := rs.NewResourceID(resourceTypeGroup, group.GroupID)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			rv = append(rv, grant.NewGrant(resource, slug, principal))
+		}
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+// Grant disables the user in Duo. A user in bypass is disabled too, and the
+// returned grant records the bypass status so Revoke can restore it. Groups are
+// given the status of the entitlement.
+func (o *accountResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if principal.Id.ResourceType == resourceTypeGroup.Id {
+		annos, err := o.grantGroupStatus(ctx, entitlement, principal)
+		if err != nil {
+			return nil, annos, err
+		}
+
+		return []*v2.Grant{grant.NewGrant(entitlement.Resource, entitlementSlug(entitlement), principal.Id)}, annos, nil
+	}
+
+	if slug := entitlementSlug(entitlement); slug != disabledEntitlement {
+		return nil, nil, fmt.Errorf("baton-duo: only groups can be granted the %s entitlement", slug)
+	}
+
+	client, user, annos, err := o.fetchUser(ctx, entitlement.Resource, principal)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	var grantOptions []grant.GrantOption
+	switch user.Status {
+	case userStatusDisabled:
+		// Already disabled, there is no earlier status to record.
+	case userStatusBypass:
+		details, err := structpb.NewStruct(map[string]interface{}{previousStatusKey: user.Status})
+		if err != nil {
+			return nil, annos, err
+		}
+		grantOptions = append(grantOptions, grant.WithAnnotation(details))
+		fallthrough
+	default:
+		updateAnnos, err := setUserStatus(ctx, client, principal.Id.Resource, userStatusDisabled)
+		annos = append(annos, updateAnnos...)
+		if err != nil {
+			return nil, annos, err
+		}
+	}
+
+	return []*v2.Grant{grant.NewGrant(entitlement.Resource, disabledEntitlement, principal.Id, grantOptions...)}, annos, nil
+}
+
+// Revoke re-enables the user in Duo, restoring the bypass status recorded on the
+// grant if there is one. Grants found by a sync don't know the earlier status,
+// so those users are made active. A user that was re-enabled in the meantime is
+// left alone, so a status set since isn't overwritten. Groups are made active
+// the same way.
+func (o *accountResourceType) Revoke(ctx context.Context, revokedGrant *v2.Grant) (annotations.Annotations, error) {
+	if revokedGrant.Principal.Id.ResourceType == resourceTypeGroup.Id {
+		return o.revokeGroupStatus(ctx, revokedGrant)
+	}
+
+	client, user, annos, err := o.fetchUser(ctx, revokedGrant.Entitlement.Resource, revokedGrant.Principal)
+	if err != nil {
+		return annos, err
+	}
+
+	if user.Status != userStatusDisabled {
+		ctxzap.Extract(ctx).Debug(
+			"baton-duo: user is no longer disabled",
+			zap.String("user_id", user.UserID),
+			zap.String("status", user.Status),
+		)
+		return annos, nil
+	}
+
+	updateAnnos, err := setUserStatus(ctx, client, user.UserID, previousStatus(revokedGrant))
+	annos = append(annos, updateAnnos...)
+	return annos, err
+}
+
+// previousStatus returns the status a disabled user had before the connector
+// disabled them, "active" unless the grant recorded otherwise.
+func previousStatus(disabledGrant *v2.Grant) string {
+	details := &structpb.Struct{}
+	annos := annotations.Annotations(disabledGrant.GetAnnotations())
+	ok, err := annos.Pick(details)
+	if err != nil || !ok {
+		return userStatusActive
+	}
+
+	if details.GetFields()[previousStatusKey].GetStringValue() == userStatusBypass {
+		return userStatusBypass
+	}
+
+	return userStatusActive
+}
+
+// fetchUser returns the principal's current Duo user, with the client of the
+// account it belongs to.
+func (o *accountResourceType) fetchUser(ctx context.Context, account *v2.Resource, principal *v2.Resource) (*duo.Client, duo.User, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		l.Warn(
+			"baton-duo: only users can be disabled",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, duo.User{}, nil, fmt.Errorf("baton-duo: only users can be disabled")
+	}
+
+	client, annos, err := o.accounts.forAccount(ctx, account.Id.Resource)
+	if err != nil {
+		return nil, duo.User{}, annos, err
+	}
+
+	user, userAnnos, err := client.GetUser(ctx, principal.Id.Resource)
+	annos = append(annos, userAnnos...)
+	if err != nil {
+		return nil, duo.User{}, annos, wrapError(err, "baton-duo: error fetching user")
+	}
+
+	return client, user, annos, nil
+}
+
+// grantGroupStatus sets the group's status to the entitlement's.
+func (o *accountResourceType) grantGroupStatus(ctx context.Context, entitlement *v2.Entitlement, principal *v2.Resource) (annotations.Annotations, error) {
+	groupStatus, ok := groupStatusEntitlements[entitlementSlug(entitlement)]
+	if !ok {
+		return nil, fmt.Errorf("baton-duo: groups can't be granted the %s entitlement", entitlementSlug(entitlement))
+	}
+
+	client, annos, err := o.accounts.forAccount(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return annos, err
+	}
+
+	updateAnnos, err := setGroupStatus(ctx, client, principal.Id.Resource, groupStatus)
+	annos = append(annos, updateAnnos...)
+	return annos, err
+}
+
+// revokeGroupStatus makes the group active, unless its status changed since.
+func (o *accountResourceType) revokeGroupStatus(ctx context.Context, revokedGrant *v2.Grant) (annotations.Annotations, error) {
+	groupStatus, ok := groupStatusEntitlements[entitlementSlug(revokedGrant.Entitlement)]
+	if !ok {
+		return nil, fmt.Errorf("baton-duo: groups can't have the %s entitlement revoked", entitlementSlug(revokedGrant.Entitlement))
+	}
+
+	client, annos, err := o.accounts.forAccount(ctx, revokedGrant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return annos, err
+	}
+
+	group, groupAnnos, err := client.GetGroup(ctx, revokedGrant.Principal.Id.Resource)
+	annos = append(annos, groupAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error fetching group")
+	}
+
+	if group.Status != groupStatus {
+		ctxzap.Extract(ctx).Debug(
+			"baton-duo: group status changed since it was granted",
+			zap.String("group_id", group.GroupID),
+			zap.String("status", group.Status),
+		)
+		return annos, nil
+	}
+
+	updateAnnos, err := setGroupStatus(ctx, client, group.GroupID, groupStatusActive)
+	annos = append(annos, updateAnnos...)
+	return annos, err
+}
+
+func setGroupStatus(ctx context.Context, client *duo.Client, groupId string, groupStatus string) (annotations.Annotations, error) {
+	_, annos, err := client.UpdateGroup(ctx, groupId, duo.GroupParams{Status: groupStatus})
+	if err != nil {
+		return annos, wrapError(err, fmt.Sprintf("baton-duo: error setting group status to %s", groupStatus))
+	}
+
+	return annos, nil
+}
+
+func setUserStatus(ctx context.Context, client *duo.Client, userId string, userStatus string) (annotations.Annotations, error) {
+	_, annos, err := client.UpdateUser(ctx, userId, duo.UserParams{Status: userStatus})
+	if err != nil {
+		return annos, wrapError(err, fmt.Sprintf("baton-duo: error setting user status to %s", userStatus))
+	}
+
+	return annos, nil
 }
 
-func accountBuilder(client *duo.Client, integrationKey string) *accountResourceType {
+func accountBuilder(accounts *accountClients, integrationKey string, disabledUsers *grantCache, caches ...syncCache) *accountResourceType {
 	return &accountResourceType{
 		resourceType:   resourceTypeAccount,
-		client:         client,
+		accounts:       accounts,
 		integrationKey: integrationKey,
+		disabledUsers:  disabledUsers,
+		caches:         append(caches, disabledUsers),
 	}
 }
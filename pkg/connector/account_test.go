@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+const testIntegrationKey = "DIXXXXXXXXXXXXXXXXXX"
+
+// pagedUsers serves users over two pages.
+func pagedUsers(users ...duo.User) testRoute {
+	return func(r *http.Request) (interface{}, string) {
+		if r.URL.Query().Get("offset") == "0" {
+			return users[:1], "1"
+		}
+		return users[1:], ""
+	}
+}
+
+// accountGrants reads every page of the account's grants, returning the
+// principal IDs by entitlement.
+func accountGrants(t *testing.T, o *accountResourceType, account *v2.Resource) map[string][]string {
+	t.Helper()
+
+	rv := make(map[string][]string)
+	token := &pagination.Token{}
+	for {
+		grants, next, _, err := o.Grants(context.Background(), account, token)
+		if err != nil {
+			t.Fatalf("Grants() error = %v", err)
+		}
+		for _, g := range grants {
+			slug := g.Entitlement.Id[strings.LastIndex(g.Entitlement.Id, ":")+1:]
+			rv[slug] = append(rv[slug], g.Principal.Id.Resource)
+		}
+		if next == "" {
+			return rv
+		}
+		token = &pagination.Token{Token: next}
+	}
+}
+
+func TestAccountGrantsFromListedUsers(t *testing.T) {
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/users": pagedUsers(
+			duo.User{UserID: "U1", Username: "alice", Status: userStatusActive},
+			duo.User{UserID: "U2", Username: "bob", Status: userStatusDisabled},
+		),
+		"/admin/v1/groups": respond([]duo.Group{}),
+	})
+	accounts := newAccountClients(d.client, false)
+	disabledUsers := newGrantCache()
+	users := userBuilder(accounts, testIntegrationKey, newResourceCache(), newResourceCache(), disabledUsers, duo.BypassCodeParams{})
+	account := testAccount
+
+	token := &pagination.Token{}
+	for {
+		_, next, _, err := users.List(context.Background(), account.Id, token)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Token: next}
+	}
+
+	grants := accountGrants(t, accountBuilder(accounts, testIntegrationKey, disabledUsers), account)
+	if ids := grants[disabledEntitlement]; len(ids) != 1 || ids[0] != "U2" {
+		t.Errorf("disabled = %v; want [U2]", ids)
+	}
+	if got := d.count("/admin/v1/users"); got != 2 {
+		t.Errorf("users were requested %d times; want 2, only by List", got)
+	}
+}
+
+func TestAccountGrantsWithoutListedUsers(t *testing.T) {
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/users": pagedUsers(
+			duo.User{UserID: "U1", Username: "alice", Status: userStatusDisabled},
+			duo.User{UserID: "U2", Username: "bob", Status: userStatusActive},
+			duo.User{UserID: "U3", Username: "carol", Status: userStatusDisabled},
+		),
+		"/admin/v1/groups": respond([]duo.Group{}),
+	})
+	account := testAccount
+	newUsers := func(accounts *accountClients, disabledUsers *grantCache) *userResourceType {
+		return userBuilder(accounts, testIntegrationKey, newResourceCache(), newResourceCache(), disabledUsers, duo.BypassCodeParams{})
+	}
+
+	// The sync is interrupted after the first page of users, and resumed by a
+	// new process from the second one.
+	_, next, _, err := newUsers(newAccountClients(d.client, false), newGrantCache()).List(context.Background(), account.Id, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	accounts := newAccountClients(d.client, false)
+	disabledUsers := newGrantCache()
+	_, _, _, err = newUsers(accounts, disabledUsers).List(context.Background(), account.Id, &pagination.Token{Token: next})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	grants := accountGrants(t, accountBuilder(accounts, testIntegrationKey, disabledUsers), account)
+	if ids := grants[disabledEntitlement]; len(ids) != 2 || ids[0] != "U1" || ids[1] != "U3" {
+		t.Errorf("disabled = %v; want [U1 U3]", ids)
+	}
+}
+
+func TestAccountGrantsGroupStatus(t *testing.T) {
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/users": respond([]duo.User{}),
+		"/admin/v1/groups": respond([]duo.Group{
+			{GroupID: "G1", Name: "Engineering", Status: groupStatusActive},
+			{GroupID: "G2", Name: "Contractors", Status: groupStatusDisabled},
+			{GroupID: "G3", Name: "Kiosks", Status: groupStatusBypass},
+		}),
+	})
+
+	account := testAccount
+	grants := accountGrants(t, accountBuilder(newAccountClients(d.client, false), testIntegrationKey, newGrantCache()), account)
+
+	if ids := grants[disabledEntitlement]; len(ids) != 1 || ids[0] != "G2" {
+		t.Errorf("disabled = %v; want [G2]", ids)
+	}
+	if ids := grants[bypassEntitlement]; len(ids) != 1 || ids[0] != "G3" {
+		t.Errorf("bypass = %v; want [G3]", ids)
+	}
+}
+
+func TestAccountRevokeGroupStatus(t *testing.T) {
+	var updates []string
+	groupStatus := groupStatusDisabled
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v2/groups/G1": func(*http.Request) (interface{}, string) {
+			return duo.Group{GroupID: "G1", Name: "Contractors", Status: groupStatus}, ""
+		},
+		"/admin/v1/groups/G1": func(r *http.Request) (interface{}, string) {
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("ParseForm() error = %v", err)
+			}
+			updates = append(updates, r.PostForm.Get("status"))
+			return duo.Group{GroupID: "G1", Name: "Contractors", Status: r.PostForm.Get("status")}, ""
+		},
+	})
+	o := accountBuilder(newAccountClients(d.client, false), testIntegrationKey, newGrantCache())
+
+	account := testAccount
+	group := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeGroup.Id, Resource: "G1"}}
+	disabled := &v2.Grant{
+		Entitlement: &v2.Entitlement{Id: resourceTypeAccount.Id + ":" + testIntegrationKey + ":" + disabledEntitlement, Resource: account},
+		Principal:   group,
+	}
+
+	if _, err := o.Revoke(context.Background(), disabled); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if len(updates) != 1 || updates[0] != groupStatusActive {
+		t.Errorf("status updates = %v; want [active]", updates)
+	}
+
+	// A group put in bypass since is left alone.
+	groupStatus = groupStatusBypass
+	if _, err := o.Revoke(context.Background(), disabled); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if len(updates) != 1 {
+		t.Errorf("status updates = %v; want only the first revoke's", updates)
+	}
+}
@@ -8,13 +8,19 @@ import (
 	"github.com/conductorone/baton-duo/pkg/duo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type adminResourceType struct {
 	resourceType *v2.ResourceType
-	client       *duo.Client
+	accounts     *accountClients
+	admins       *resourceCache
 }
 
 func (o *adminResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -69,9 +75,15 @@ func (o *adminResourceType) List(ctx context.Context, parentId *v2.ResourceId, t
 	if err != nil {
 		return nil, "", nil, err
 	}
-	admins, offset, err := o.client.GetAdmins(ctx, bag.PageToken())
+	client, annos, err := o.accounts.forParent(ctx, parentId)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("duo-connector: failed to list admins: %w", err)
+		return nil, "", annos, err
+	}
+
+	admins, offset, listAnnos, err := client.GetAdmins(ctx, bag.PageToken())
+	annos = append(annos, listAnnos...)
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list admins")
 	}
 
 	if offset != "" {
@@ -88,10 +100,11 @@ func (o *adminResourceType) List(ctx context.Context, parentId *v2.ResourceId, t
 		if err != nil {
 			return nil, "", nil, err
 		}
+		o.admins.set(ar)
 		rv = append(rv, ar)
 	}
 
-	return rv, pageToken, nil, nil
+	return rv, pageToken, annos, nil
 }
 
 func (o *adminResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -102,9 +115,132 @@ func (o *adminResourceType) Grants(ctx context.Context, resource *v2.Resource, t
 	return nil, "", nil, nil
 }
 
-func adminBuilder(client *duo.Client) *adminResourceType {
+func (o *adminResourceType) Create(_ context.Context, _ *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	return nil, nil, status.Error(codes.Unimplemented, "baton-duo: admins are created through account provisioning")
+}
+
+// Delete removes a Duo admin.
+func (o *adminResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != resourceTypeAdmin.Id {
+		return nil, fmt.Errorf("baton-duo: only admins can be deleted by this resource type")
+	}
+
+	client, annos, err := o.accounts.forResource(ctx, o.admins, resourceId, lookupAdmin)
+	if err != nil {
+		return annos, err
+	}
+
+	deleteAnnos, err := client.DeleteAdmin(ctx, resourceId.Resource)
+	annos = append(annos, deleteAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error deleting admin")
+	}
+
+	return annos, nil
+}
+
+// createAdminAccount provisions a new Duo admin. When Duo doesn't email the
+// activation link itself, the link is returned as plaintext data for the requester.
+func createAdminAccount(
+	ctx context.Context,
+	client *duo.Client,
+	admins *resourceCache,
+	parentId *v2.ResourceId,
+	accountInfo *v2.AccountInfo,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	params := adminParamsFromAccountInfo(accountInfo)
+	if params.Email == "" {
+		return nil, nil, nil, status.Error(codes.InvalidArgument, "baton-duo: an email is required to create an admin")
+	}
+
+	admin, annos, err := client.CreateAdmin(ctx, params)
+	if err != nil {
+		return nil, nil, annos, wrapError(err, "baton-duo: error creating admin")
+	}
+
+	ar, err := adminResource(ctx, &admin, parentId)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+	admins.set(ar)
+
+	result := &v2.CreateAccountResponse_SuccessResult{
+		Resource:              ar,
+		IsCreateAccountResult: true,
+	}
+	if params.SendEmail {
+		return result, nil, annos, nil
+	}
+
+	// The admin exists at this point, so a missing link isn't a provisioning failure.
+	link, linkAnnos, err := client.CreateAdminActivationLink(ctx, admin.AdminID)
+	annos = append(annos, linkAnnos...)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn(
+			"baton-duo: unable to create admin activation link",
+			zap.String("admin_id", admin.AdminID),
+			zap.Error(err),
+		)
+		return result, nil, annos, nil
+	}
+	if link == "" {
+		return result, nil, annos, nil
+	}
+
+	activationLink := &v2.PlaintextData{
+		Name:        "activation_link",
+		Description: fmt.Sprintf("Duo admin activation link for %s", admin.Email),
+		Bytes:       []byte(link),
+	}
+
+	return result, []*v2.PlaintextData{activationLink}, annos, nil
+}
+
+func adminParamsFromAccountInfo(accountInfo *v2.AccountInfo) duo.AdminParams {
+	profile := accountInfo.GetProfile()
+	profileValue := func(key string) string {
+		value, _ := rs.GetProfileStringValue(profile, key)
+		return value
+	}
+
+	var email string
+	for _, e := range accountInfo.GetEmails() {
+		if email == "" || e.GetIsPrimary() {
+			email = e.GetAddress()
+		}
+	}
+	if email == "" {
+		email = accountInfo.GetLogin()
+	}
+
+	name := profileValue("name")
+	if name == "" {
+		name = strings.TrimSpace(fmt.Sprintf("%s %s", profileValue("first_name"), profileValue("last_name")))
+	}
+
+	params := duo.AdminParams{
+		Email: email,
+		Name:  name,
+		Phone: profileValue("phone"),
+		Role:  profileValue("role"),
+	}
+
+	fields := profile.GetFields()
+	if v, ok := fields["restricted_by_admin_units"]; ok {
+		restricted := v.GetBoolValue()
+		params.RestrictedByAdminUnits = &restricted
+	}
+	if v, ok := fields["send_email"]; ok {
+		params.SendEmail = v.GetBoolValue()
+	}
+
+	return params
+}
+
+func adminBuilder(accounts *accountClients, admins *resourceCache) *adminResourceType {
 	return &adminResourceType{
 		resourceType: resourceTypeAdmin,
-		client:       client,
+		accounts:     accounts,
+		admins:       admins,
 	}
 }
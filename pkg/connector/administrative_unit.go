@@ -0,0 +1,266 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	adminOfEntitlement             = "admin"
+	containsGroupEntitlement       = "group"
+	containsApplicationEntitlement = "application"
+)
+
+// administrativeUnitMemberTypes maps each entitlement of an administrative
+// unit to the resource type it is granted to and the kind of member Duo
+// manages it as.
+var administrativeUnitMemberTypes = map[string]struct {
+	resourceType *v2.ResourceType
+	memberType   string
+}{
+	adminOfEntitlement:             {resourceTypeAdmin, duo.AdministrativeUnitAdmin},
+	containsGroupEntitlement:       {resourceTypeGroup, duo.AdministrativeUnitGroup},
+	containsApplicationEntitlement: {resourceTypeApplication, duo.AdministrativeUnitIntegration},
+}
+
+type administrativeUnitResourceType struct {
+	resourceType *v2.ResourceType
+	client       *duo.Client
+	members      *grantCache
+}
+
+func (o *administrativeUnitResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func administrativeUnitBuilder(client *duo.Client) *administrativeUnitResourceType {
+	return &administrativeUnitResourceType{
+		resourceType: resourceTypeAdministrativeUnit,
+		client:       client,
+		members:      newGrantCache(),
+	}
+}
+
+// Create a new connector resource for a Duo administrative unit.
+func administrativeUnitResource(ctx context.Context, unit *duo.AdministrativeUnit, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	description := describe(
+		"description", unit.Description,
+		"restrict_by_groups", strconv.FormatBool(unit.RestrictByGroups),
+		"restrict_by_integrations", strconv.FormatBool(unit.RestrictByIntegrations),
+	)
+
+	ret, err := rs.NewResource(
+		unit.Name,
+		resourceTypeAdministrativeUnit,
+		unit.AdminUnitID,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (o *administrativeUnitResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	var pageToken string
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeAdministrativeUnit.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	o.members.startList(bag)
+
+	units, offset, annos, err := o.client.GetAdministrativeUnits(ctx, bag.PageToken())
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list administrative units")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Resource
+	for _, unit := range units {
+		unitCopy := unit
+		ur, err := administrativeUnitResource(ctx, &unitCopy, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		members, err := administrativeUnitMembers(&unitCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		o.members.set(unit.AdminUnitID, members)
+		rv = append(rv, ur)
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+func (o *administrativeUnitResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			adminOfEntitlement,
+			ent.WithGrantableTo(resourceTypeAdmin),
+			ent.WithDescription(fmt.Sprintf("Admin restricted to the %s administrative unit in Duo", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Administrative Unit %s", resource.DisplayName, adminOfEntitlement)),
+		),
+		ent.NewAssignmentEntitlement(
+			resource,
+			containsGroupEntitlement,
+			ent.WithGrantableTo(resourceTypeGroup),
+			ent.WithDescription(fmt.Sprintf("Group managed by the admins of the %s administrative unit in Duo", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Administrative Unit %s", resource.DisplayName, containsGroupEntitlement)),
+		),
+		ent.NewAssignmentEntitlement(
+			resource,
+			containsApplicationEntitlement,
+			ent.WithGrantableTo(resourceTypeApplication),
+			ent.WithDescription(fmt.Sprintf("Application managed by the admins of the %s administrative unit in Duo", resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Administrative Unit %s", resource.DisplayName, containsApplicationEntitlement)),
+		),
+	}, "", nil, nil
+}
+
+// Grants gives each admin, group and application of the unit the entitlement
+// matching its resource type.
+func (o *administrativeUnitResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	members, annos, err := o.members.lookup(ctx, resource.Id.Resource, o.fetchMembers)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	var rv []*v2.Grant
+	for _, principal := range members {
+		for slug, member := range administrativeUnitMemberTypes {
+			if member.resourceType.Id == principal.ResourceType {
+				rv = append(rv, grant.NewGrant(resource, slug, principal))
+			}
+		}
+	}
+
+	return rv, "", annos, nil
+}
+
+// fetchMembers fetches the members of an administrative unit that wasn't listed.
+func (o *administrativeUnitResourceType) fetchMembers(ctx context.Context, adminUnitId string) ([]*v2.ResourceId, annotations.Annotations, error) {
+	unit, annos, err := o.client.GetAdministrativeUnit(ctx, adminUnitId)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to fetch administrative unit")
+	}
+
+	members, err := administrativeUnitMembers(&unit)
+	return members, annos, err
+}
+
+// administrativeUnitMembers returns the admins, groups and applications of an
+// administrative unit.
+func administrativeUnitMembers(unit *duo.AdministrativeUnit) ([]*v2.ResourceId, error) {
+	var members []*v2.ResourceId
+	for _, member := range []struct {
+		resourceType *v2.ResourceType
+		ids          []string
+	}{
+		{resourceTypeAdmin, unit.Admins},
+		{resourceTypeGroup, unit.Groups},
+		{resourceTypeApplication, unit.Integrations},
+	} {
+		for _, id := range member.ids {
+			principal, err := rs.NewResourceID(member.resourceType, id)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, principal)
+		}
+	}
+
+	return members, nil
+}
+
+// Grant adds the admin, group or application to the administrative unit,
+// depending on the entitlement.
+func (o *administrativeUnitResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	memberType, err := administrativeUnitMemberType(entitlement, principal)
+	if err != nil {
+		l.Warn(
+			"baton-duo: principal can't be granted this administrative unit entitlement",
+			zap.String("entitlement_id", entitlement.Id),
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, err
+	}
+
+	annos, err := o.client.AddAdministrativeUnitMember(ctx, entitlement.Resource.Id.Resource, memberType, principal.Id.Resource)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error adding administrative unit member")
+	}
+
+	return annos, nil
+}
+
+func (o *administrativeUnitResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	entitlement := grant.Entitlement
+	principal := grant.Principal
+
+	memberType, err := administrativeUnitMemberType(entitlement, principal)
+	if err != nil {
+		l.Warn(
+			"baton-duo: principal can't have this administrative unit entitlement revoked",
+			zap.String("entitlement_id", entitlement.Id),
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, err
+	}
+
+	annos, err := o.client.RemoveAdministrativeUnitMember(ctx, entitlement.Resource.Id.Resource, memberType, principal.Id.Resource)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error removing administrative unit member")
+	}
+
+	return annos, nil
+}
+
+// administrativeUnitMemberType returns the kind of administrative unit member
+// the entitlement manages, checking that the principal is of the matching type.
+func administrativeUnitMemberType(entitlement *v2.Entitlement, principal *v2.Resource) (string, error) {
+	slug := entitlementSlug(entitlement)
+
+	member, ok := administrativeUnitMemberTypes[slug]
+	if !ok {
+		return "", fmt.Errorf("baton-duo: unknown administrative unit entitlement %s", slug)
+	}
+
+	if principal.Id.ResourceType != member.resourceType.Id {
+		return "", fmt.Errorf("baton-duo: only %s resources can be granted the %s entitlement", member.resourceType.Id, slug)
+	}
+
+	return member.memberType, nil
+}
@@ -0,0 +1,57 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestAdministrativeUnitGrantsFromList(t *testing.T) {
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/administrative_units": respond([]duo.AdministrativeUnit{
+			{
+				AdminUnitID:  "DU1",
+				Name:         "EMEA",
+				Admins:       []string{"DE1"},
+				Groups:       []string{"G1", "G2"},
+				Integrations: []string{"DI1"},
+			},
+		}),
+	})
+	o := administrativeUnitBuilder(d.client)
+
+	units, _, _, err := o.List(context.Background(), testAccount.Id, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(units) != 1 {
+		t.Fatalf("List() returned %d units; want 1", len(units))
+	}
+
+	grants, _, _, err := o.Grants(context.Background(), units[0], &pagination.Token{})
+	if err != nil {
+		t.Fatalf("Grants() error = %v", err)
+	}
+
+	got := make(map[string][]string)
+	for _, g := range grants {
+		slug := g.Entitlement.Id[strings.LastIndex(g.Entitlement.Id, ":")+1:]
+		got[slug] = append(got[slug], g.Principal.Id.Resource)
+	}
+	for slug, want := range map[string][]string{
+		adminOfEntitlement:             {"DE1"},
+		containsGroupEntitlement:       {"G1", "G2"},
+		containsApplicationEntitlement: {"DI1"},
+	} {
+		if !slices.Equal(got[slug], want) {
+			t.Errorf("%s = %v; want %v", slug, got[slug], want)
+		}
+	}
+	if n := d.count("/admin/v1/administrative_units/DU1"); n != 0 {
+		t.Errorf("listed unit was fetched %d times; want 0", n)
+	}
+}
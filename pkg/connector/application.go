@@ -0,0 +1,290 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const accessEntitlement = "access"
+
+type applicationResourceType struct {
+	resourceType *v2.ResourceType
+	client       *duo.Client
+	allowed      *grantCache
+}
+
+func (o *applicationResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func applicationBuilder(client *duo.Client) *applicationResourceType {
+	return &applicationResourceType{
+		resourceType: resourceTypeApplication,
+		client:       client,
+		allowed:      newGrantCache(),
+	}
+}
+
+// Create a new connector resource for a Duo protected application.
+func applicationResource(ctx context.Context, integration *duo.Integration, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := make(map[string]interface{})
+	profile["integration_key"] = integration.IntegrationKey
+	profile["name"] = integration.Name
+	profile["type"] = integration.Type
+	profile["policy_key"] = integration.PolicyKey
+	profile["enroll_policy"] = integration.EnrollPolicy
+	// Without allowed groups, every user can authenticate to the application.
+	profile["access_restricted"] = len(integration.GroupsAllowed) > 0
+
+	appTraits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		integration.Name,
+		resourceTypeApplication,
+		integration.IntegrationKey,
+		appTraits,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(integration.Notes),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (o *applicationResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	var pageToken string
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeApplication.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	o.allowed.startList(bag)
+
+	integrations, offset, annos, err := o.client.GetIntegrations(ctx, bag.PageToken())
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list integrations")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Resource
+	for _, integration := range integrations {
+		integrationCopy := integration
+		ar, err := applicationResource(ctx, &integrationCopy, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		allowed, err := allowedGroups(&integrationCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		o.allowed.set(integration.IntegrationKey, allowed)
+		rv = append(rv, ar)
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+func (o *applicationResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeGroup),
+		ent.WithDescription(fmt.Sprintf("Allowed to authenticate to %s in Duo", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Application %s", resource.DisplayName, accessEntitlement)),
+	}
+
+	en := ent.NewAssignmentEntitlement(resource, accessEntitlement, assignmentOptions...)
+	rv = append(rv, en)
+
+	return rv, "", nil, nil
+}
+
+// Grants gives access to the groups allowed on the application, expanded to
+// their members. Applications open to every user have no grants.
+func (o *applicationResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	allowed, annos, err := o.allowed.lookup(ctx, resource.Id.Resource, o.fetchAllowed)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	var rv []*v2.Grant
+	for _, principal := range allowed {
+		rv = append(rv, grant.NewGrant(
+			resource,
+			accessEntitlement,
+			principal,
+			grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: []string{fmt.Sprintf("%s:%s:%s", resourceTypeGroup.Id, principal.Resource, memberEntitlement)},
+			}),
+		))
+	}
+
+	return rv, "", annos, nil
+}
+
+// fetchAllowed fetches the allowed groups of an integration that wasn't listed.
+func (o *applicationResourceType) fetchAllowed(ctx context.Context, integrationKey string) ([]*v2.ResourceId, annotations.Annotations, error) {
+	integration, annos, err := o.client.GetIntegrationByKey(ctx, integrationKey)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to fetch integration")
+	}
+
+	allowed, err := allowedGroups(&integration)
+	return allowed, annos, err
+}
+
+// allowedGroups returns the groups allowed to authenticate to an integration.
+func allowedGroups(integration *duo.Integration) ([]*v2.ResourceId, error) {
+	var allowed []*v2.ResourceId
+	for _, groupId := range integration.GroupsAllowed {
+		principal, err := rs.NewResourceID(resourceTypeGroup, groupId)
+		if err != nil {
+			return nil, err
+		}
+		allowed = append(allowed, principal)
+	}
+
+	return allowed, nil
+}
+
+func (o *applicationResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeGroup.Id {
+		l.Warn(
+			"baton-duo: only groups can be granted application access",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-duo: only groups can be granted application access")
+	}
+
+	groupId := principal.Id.Resource
+	return o.updateGroupsAllowed(ctx, entitlement.Resource.Id.Resource, func(groupIds []string) ([]string, error) {
+		if slices.Contains(groupIds, groupId) {
+			return nil, nil
+		}
+
+		// Duo treats an empty list as allowing everyone, so allowing one group
+		// would lock every other user out of the application.
+		if len(groupIds) == 0 {
+			return nil, status.Errorf(
+				codes.FailedPrecondition,
+				"baton-duo: can't grant access to %s, it allows every user and would then only allow this group",
+				entitlement.Resource.DisplayName,
+			)
+		}
+
+		return append(slices.Clone(groupIds), groupId), nil
+	})
+}
+
+func (o *applicationResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	entitlement := grant.Entitlement
+	principal := grant.Principal
+
+	if principal.Id.ResourceType != resourceTypeGroup.Id {
+		l.Warn(
+			"baton-duo: only groups can have application access revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-duo: only groups can have application access revoked")
+	}
+
+	groupId := principal.Id.Resource
+	return o.updateGroupsAllowed(ctx, entitlement.Resource.Id.Resource, func(groupIds []string) ([]string, error) {
+		if !slices.Contains(groupIds, groupId) {
+			return nil, nil
+		}
+
+		// Duo treats an empty list as allowing everyone, so removing the last
+		// group would open the application instead of closing it.
+		if len(groupIds) == 1 {
+			return nil, status.Errorf(
+				codes.FailedPrecondition,
+				"baton-duo: can't revoke the last allowed group of %s, it would allow every user",
+				entitlement.Resource.DisplayName,
+			)
+		}
+
+		return slices.DeleteFunc(slices.Clone(groupIds), func(id string) bool { return id == groupId }), nil
+	})
+}
+
+// updateGroupsAllowed read-modify-writes the groups allowed on an application.
+// Duo has no conditional update, so the integration is read again right before
+// writing, and the request is aborted without writing if its groups changed in
+// the meantime. modify returns nil when there is nothing to change.
+func (o *applicationResourceType) updateGroupsAllowed(
+	ctx context.Context,
+	integrationKey string,
+	modify func(groupIds []string) ([]string, error),
+) (annotations.Annotations, error) {
+	integration, annos, err := o.client.GetIntegrationByKey(ctx, integrationKey)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error fetching integration")
+	}
+
+	groupIds, err := modify(integration.GroupsAllowed)
+	if err != nil || groupIds == nil {
+		return annos, err
+	}
+
+	current, currentAnnos, err := o.client.GetIntegrationByKey(ctx, integrationKey)
+	annos = append(annos, currentAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error fetching integration")
+	}
+	if !sameGroups(current.GroupsAllowed, integration.GroupsAllowed) {
+		return annos, status.Errorf(codes.Aborted, "baton-duo: allowed groups of integration %s changed concurrently, retry the request", integrationKey)
+	}
+
+	_, updateAnnos, err := o.client.UpdateIntegrationGroupsAllowed(ctx, integrationKey, groupIds)
+	annos = append(annos, updateAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error updating allowed groups")
+	}
+
+	return annos, nil
+}
+
+// sameGroups reports whether two lists hold the same group IDs, in any order.
+func sameGroups(a []string, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
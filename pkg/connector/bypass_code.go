@@ -0,0 +1,195 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type bypassCodeResourceType struct {
+	resourceType *v2.ResourceType
+	client       *duo.Client
+	holders      *grantCache
+}
+
+func (o *bypassCodeResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// Create a new connector resource for a Duo bypass code. Duo never returns the
+// code itself once issued, only when it was created and how long it lasts.
+func bypassCodeResource(ctx context.Context, bypassCode *duo.BypassCode, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	var created, username string
+	if bypassCode.Created > 0 {
+		created = time.Unix(bypassCode.Created, 0).UTC().Format(time.RFC3339)
+	}
+	if bypassCode.User != nil {
+		username = bypassCode.User.Username
+	}
+
+	expiration := "never"
+	if bypassCode.Expiration != nil {
+		expiration = time.Unix(*bypassCode.Expiration, 0).UTC().Format(time.RFC3339)
+	}
+	reuseCount := "unlimited"
+	if bypassCode.ReuseCount != nil {
+		reuseCount = strconv.FormatInt(*bypassCode.ReuseCount, 10)
+	}
+
+	description := describe(
+		"user", username,
+		"created", created,
+		"expiration", expiration,
+		"reuse_count", reuseCount,
+		"created_by", bypassCode.AdminEmail,
+	)
+
+	displayName := bypassCode.BypassCodeID
+	if username != "" {
+		displayName = fmt.Sprintf("%s bypass code %s", username, created)
+	}
+
+	ret, err := rs.NewResource(
+		displayName,
+		resourceTypeBypassCode,
+		bypassCode.BypassCodeID,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (o *bypassCodeResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	var pageToken string
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeBypassCode.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	o.holders.startList(bag)
+
+	bypassCodes, offset, annos, err := o.client.GetBypassCodes(ctx, bag.PageToken())
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list bypass codes")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Resource
+	for _, bypassCode := range bypassCodes {
+		bypassCodeCopy := bypassCode
+		br, err := bypassCodeResource(ctx, &bypassCodeCopy, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		holders, err := bypassCodeHolders(&bypassCodeCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		o.holders.set(bypassCode.BypassCodeID, holders)
+		rv = append(rv, br)
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+func (o *bypassCodeResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeUser),
+		ent.WithDescription(fmt.Sprintf("Holder of %s in Duo", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, ownerEntitlement)),
+	}
+
+	en := ent.NewAssignmentEntitlement(resource, ownerEntitlement, assignmentOptions...)
+	rv = append(rv, en)
+
+	return rv, "", nil, nil
+}
+
+// Grants makes the user a bypass code was issued to its holder, so lingering
+// codes show up in that user's access.
+func (o *bypassCodeResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	rv, annos, err := o.holders.grants(ctx, resource, ownerEntitlement, o.fetchHolders)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return rv, "", annos, nil
+}
+
+// fetchHolders fetches the holder of a bypass code that wasn't listed.
+func (o *bypassCodeResourceType) fetchHolders(ctx context.Context, bypassCodeId string) ([]*v2.ResourceId, annotations.Annotations, error) {
+	bypassCode, annos, err := o.client.GetBypassCode(ctx, bypassCodeId)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to fetch bypass code")
+	}
+
+	holders, err := bypassCodeHolders(&bypassCode)
+	return holders, annos, err
+}
+
+// bypassCodeHolders returns the user a bypass code was issued to.
+func bypassCodeHolders(bypassCode *duo.BypassCode) ([]*v2.ResourceId, error) {
+	if bypassCode.User == nil {
+		return nil, nil
+	}
+
+	principal, err := rs.NewResourceID(resourceTypeUser, bypassCode.User.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*v2.ResourceId{principal}, nil
+}
+
+func (o *bypassCodeResourceType) Create(_ context.Context, _ *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	return nil, nil, status.Error(codes.Unimplemented, "baton-duo: bypass codes are issued by rotating the user's credentials")
+}
+
+// Delete revokes a bypass code before it expires or is used up.
+func (o *bypassCodeResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != resourceTypeBypassCode.Id {
+		return nil, fmt.Errorf("baton-duo: only bypass codes can be deleted by this resource type")
+	}
+
+	annos, err := o.client.DeleteBypassCode(ctx, resourceId.Resource)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error deleting bypass code")
+	}
+
+	return annos, nil
+}
+
+func bypassCodeBuilder(client *duo.Client) *bypassCodeResourceType {
+	return &bypassCodeResourceType{
+		resourceType: resourceTypeBypassCode,
+		client:       client,
+		holders:      newGrantCache(),
+	}
+}
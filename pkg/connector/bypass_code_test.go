@@ -0,0 +1,49 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestBypassCodeListAndGrants(t *testing.T) {
+	reuseCount := int64(2)
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/bypass_codes": respond([]duo.BypassCode{
+			{BypassCodeID: "DB1", AdminEmail: "admin@example.com", Created: 1700000000, ReuseCount: &reuseCount, User: &duo.User{UserID: "U1", Username: "alice"}},
+		}),
+		"/admin/v1/bypass_codes/DB2": respond(duo.BypassCode{BypassCodeID: "DB2", User: &duo.User{UserID: "U2", Username: "bob"}}),
+	})
+	o := bypassCodeBuilder(d.client)
+
+	bypassCodes, _, _, err := o.List(context.Background(), testAccount.Id, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(bypassCodes) != 1 {
+		t.Fatalf("List() returned %d bypass codes; want 1", len(bypassCodes))
+	}
+	for _, want := range []string{"expiration: never", "reuse_count: 2", "created_by: admin@example.com"} {
+		if !strings.Contains(bypassCodes[0].Description, want) {
+			t.Errorf("Description = %q; want it to contain %q", bypassCodes[0].Description, want)
+		}
+	}
+
+	if got, want := grantPrincipals(t, o, bypassCodes[0]), []string{"user:U1"}; !slices.Equal(got, want) {
+		t.Errorf("DB1 holders = %v; want %v", got, want)
+	}
+	if got := d.count("/admin/v1/bypass_codes/DB1"); got != 0 {
+		t.Errorf("listed bypass code was fetched %d times; want 0", got)
+	}
+
+	// A bypass code that wasn't listed is fetched on its own.
+	unlisted := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeBypassCode.Id, Resource: "DB2"}}
+	if got, want := grantPrincipals(t, o, unlisted), []string{"user:U2"}; !slices.Equal(got, want) {
+		t.Errorf("DB2 holders = %v; want %v", got, want)
+	}
+}
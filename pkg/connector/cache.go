@@ -0,0 +1,148 @@
+package connector
+
+import (
+	"context"
+	"sync"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+// syncCache is a cache filled while listing, which only lives for one sync.
+type syncCache interface {
+	reset()
+}
+
+// resourceCache keeps the resources built while listing, so events and
+// provisioning can find a resource without fetching it from Duo again. Caches
+// are reset when a fresh sync starts, and a resumed sync may start with them
+// empty, so a miss is looked up in Duo. A nil cache is valid and never returns a hit.
+type resourceCache struct {
+	mu   sync.RWMutex
+	byId map[string]*v2.Resource
+}
+
+func newResourceCache() *resourceCache {
+	return &resourceCache{
+		byId: make(map[string]*v2.Resource),
+	}
+}
+
+func (c *resourceCache) set(resource *v2.Resource) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byId[resource.Id.Resource] = resource
+}
+
+// reset drops the resources cached by a previous sync.
+func (c *resourceCache) reset() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byId = make(map[string]*v2.Resource)
+}
+
+func (c *resourceCache) get(id string) (*v2.Resource, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	resource, ok := c.byId[id]
+	return resource, ok
+}
+
+// grantCache keeps the principals each listed resource grants its entitlement
+// to, taken from the list payload, so Grants doesn't fetch every resource from
+// Duo again. Resources missing from the cache are still fetched one by one.
+type grantCache struct {
+	mu         sync.RWMutex
+	principals map[string][]*v2.ResourceId
+}
+
+func newGrantCache() *grantCache {
+	return &grantCache{
+		principals: make(map[string][]*v2.ResourceId),
+	}
+}
+
+func (c *grantCache) set(resourceId string, principals []*v2.ResourceId) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.principals[resourceId] = principals
+}
+
+// add appends a principal to a resource's, for grants gathered across the list
+// pages of another resource type.
+func (c *grantCache) add(resourceId string, principal *v2.ResourceId) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.principals[resourceId] = append(c.principals[resourceId], principal)
+}
+
+// delete drops a resource's principals, so it is missing from the cache again.
+func (c *grantCache) delete(resourceId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.principals, resourceId)
+}
+
+// reset drops the principals cached by a previous sync.
+func (c *grantCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.principals = make(map[string][]*v2.ResourceId)
+}
+
+func (c *grantCache) get(resourceId string) ([]*v2.ResourceId, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	principals, ok := c.principals[resourceId]
+	return principals, ok
+}
+
+// startList drops the principals cached by a previous sync when a list starts
+// again from its first page.
+func (c *grantCache) startList(bag *pagination.Bag) {
+	if bag.PageToken() == "" {
+		c.reset()
+	}
+}
+
+// principalsFetcher fetches the principals of a single resource from Duo.
+type principalsFetcher func(ctx context.Context, resourceId string) ([]*v2.ResourceId, annotations.Annotations, error)
+
+// lookup returns a resource's cached principals, or fetches them when the
+// resource wasn't listed by this process.
+func (c *grantCache) lookup(ctx context.Context, resourceId string, fetch principalsFetcher) ([]*v2.ResourceId, annotations.Annotations, error) {
+	if principals, ok := c.get(resourceId); ok {
+		return principals, nil, nil
+	}
+
+	return fetch(ctx, resourceId)
+}
+
+// grants grants the entitlement of a resource to each of its principals.
+func (c *grantCache) grants(ctx context.Context, resource *v2.Resource, entitlement string, fetch principalsFetcher) ([]*v2.Grant, annotations.Annotations, error) {
+	principals, annos, err := c.lookup(ctx, resource.Id.Resource, fetch)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	var rv []*v2.Grant
+	for _, principal := range principals {
+		rv = append(rv, grant.NewGrant(resource, entitlement, principal))
+	}
+
+	return rv, annos, nil
+}
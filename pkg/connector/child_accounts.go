@@ -0,0 +1,148 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// accountClients hands out the client for the Duo account a resource belongs
+// to. Without child accounts every resource uses the configured client. With
+// them, the configured client is an Accounts API integration and resources
+// under a child account resource use a client scoped to that child.
+type accountClients struct {
+	client        *duo.Client
+	childAccounts bool
+
+	mu       sync.Mutex
+	children map[string]*duo.Client
+}
+
+func newAccountClients(client *duo.Client, childAccounts bool) *accountClients {
+	return &accountClients{
+		client:        client,
+		childAccounts: childAccounts,
+		children:      make(map[string]*duo.Client),
+	}
+}
+
+// set records the child accounts, so their clients don't have to be looked up again.
+func (a *accountClients) set(accounts []duo.ChildAccount) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, account := range accounts {
+		a.children[account.AccountID] = a.client.ForAccount(account.AccountID, account.APIHostname)
+	}
+}
+
+// forAccount returns the client for a child account, listing the child
+// accounts when it hasn't been seen yet, e.g. when provisioning outside a sync.
+func (a *accountClients) forAccount(ctx context.Context, accountId string) (*duo.Client, annotations.Annotations, error) {
+	if !a.childAccounts {
+		return a.client, nil, nil
+	}
+
+	a.mu.Lock()
+	client, ok := a.children[accountId]
+	a.mu.Unlock()
+	if ok {
+		return client, nil, nil
+	}
+
+	accounts, annos, err := a.client.GetChildAccounts(ctx)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to list child accounts")
+	}
+	a.set(accounts)
+
+	a.mu.Lock()
+	client, ok = a.children[accountId]
+	a.mu.Unlock()
+	if !ok {
+		return nil, annos, status.Errorf(codes.NotFound, "duo-connector: unknown child account %s", accountId)
+	}
+
+	return client, annos, nil
+}
+
+// forParent returns the client for resources whose parent is parentId.
+func (a *accountClients) forParent(ctx context.Context, parentId *v2.ResourceId) (*duo.Client, annotations.Annotations, error) {
+	if parentId == nil || parentId.ResourceType != resourceTypeAccount.Id {
+		return a.client, nil, nil
+	}
+
+	return a.forAccount(ctx, parentId.Resource)
+}
+
+// resourceLookup fetches a resource by ID from an account, returning a Duo
+// not found error when the account doesn't have it.
+type resourceLookup func(ctx context.Context, client *duo.Client, id string) (annotations.Annotations, error)
+
+// forResource returns the client for a resource only known by its ID, such as
+// one being deleted. With child accounts, the account comes from the parent the
+// resource was synced under, and a resource that wasn't synced by this process,
+// e.g. in a one-shot provisioning run, is looked up in each child account.
+func (a *accountClients) forResource(
+	ctx context.Context,
+	cache *resourceCache,
+	resourceId *v2.ResourceId,
+	lookup resourceLookup,
+) (*duo.Client, annotations.Annotations, error) {
+	if !a.childAccounts {
+		return a.client, nil, nil
+	}
+
+	if resource, ok := cache.get(resourceId.Resource); ok {
+		return a.forParent(ctx, resource.ParentResourceId)
+	}
+
+	accounts, annos, err := a.client.GetChildAccounts(ctx)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to list child accounts")
+	}
+	a.set(accounts)
+
+	for _, account := range accounts {
+		client, _, err := a.forAccount(ctx, account.AccountID)
+		if err != nil {
+			return nil, annos, err
+		}
+
+		lookupAnnos, err := lookup(ctx, client, resourceId.Resource)
+		annos = append(annos, lookupAnnos...)
+		if err == nil {
+			return client, annos, nil
+		}
+		if !duo.IsNotFound(err) {
+			return nil, annos, wrapError(err, fmt.Sprintf("duo-connector: failed to look up %s in child account %s", resourceId.ResourceType, account.AccountID))
+		}
+	}
+
+	return nil, annos, status.Errorf(
+		codes.NotFound,
+		"duo-connector: %s %s wasn't found in any child account",
+		resourceId.ResourceType,
+		resourceId.Resource,
+	)
+}
+
+func lookupUser(ctx context.Context, client *duo.Client, id string) (annotations.Annotations, error) {
+	_, annos, err := client.GetUser(ctx, id)
+	return annos, err
+}
+
+func lookupAdmin(ctx context.Context, client *duo.Client, id string) (annotations.Annotations, error) {
+	_, annos, err := client.GetAdmin(ctx, id)
+	return annos, err
+}
+
+func lookupGroup(ctx context.Context, client *duo.Client, id string) (annotations.Annotations, error) {
+	_, annos, err := client.GetGroup(ctx, id)
+	return annos, err
+}
@@ -2,7 +2,6 @@ package connector
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/conductorone/baton-duo/pkg/duo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -47,20 +46,69 @@ var (
 			v2.ResourceType_TRAIT_ROLE,
 		},
 	}
+	resourceTypePhone = &v2.ResourceType{
+		Id:          "phone",
+		DisplayName: "Phone",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
+	resourceTypeToken = &v2.ResourceType{
+		Id:          "token",
+		DisplayName: "Token",
+	}
+	resourceTypeWebAuthnCredential = &v2.ResourceType{
+		Id:          "webauthn_credential",
+		DisplayName: "WebAuthn Credential",
+	}
+	resourceTypeBypassCode = &v2.ResourceType{
+		Id:          "bypass_code",
+		DisplayName: "Bypass Code",
+	}
+	resourceTypeApplication = &v2.ResourceType{
+		Id:          "application",
+		DisplayName: "Application",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
+	}
+	resourceTypeAdministrativeUnit = &v2.ResourceType{
+		Id:          "administrative_unit",
+		DisplayName: "Administrative Unit",
+	}
+	resourceTypePolicy = &v2.ResourceType{
+		Id:          "policy",
+		DisplayName: "Policy",
+	}
 )
 
 type Duo struct {
-	client         *duo.Client
-	integrationKey string
+	client             *duo.Client
+	accounts           *accountClients
+	integrationKey     string
+	adminFallbackRole  string
+	telephonyUsageDays int
+	bypassCodeParams   duo.BypassCodeParams
+	users              *resourceCache
+	groups             *resourceCache
+	admins             *resourceCache
+	disabledUsers      *grantCache
 }
 
 func (d *Duo) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		userBuilder(d.client),
-		groupBuilder(d.client),
-		adminBuilder(d.client),
-		accountBuilder(d.client, d.integrationKey),
-		roleBuilder(d.client),
+		userBuilder(d.accounts, d.integrationKey, d.users, d.admins, d.disabledUsers, d.bypassCodeParams),
+		groupBuilder(d.accounts, d.integrationKey, d.groups),
+		adminBuilder(d.accounts, d.admins),
+		accountBuilder(d.accounts, d.integrationKey, d.disabledUsers, d.users, d.groups, d.admins),
+		roleBuilder(d.client, d.adminFallbackRole),
+		phoneBuilder(d.client, d.telephonyUsageDays),
+		tokenBuilder(d.client),
+		webAuthnCredentialBuilder(d.client),
+		bypassCodeBuilder(d.client),
+		applicationBuilder(d.client),
+		administrativeUnitBuilder(d.client),
+		policyBuilder(d.client),
 	}
 }
 
@@ -68,28 +116,54 @@ func (d *Duo) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSy
 func (d *Duo) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
 		DisplayName: "Duo",
-		Description: "Connector syncing users, groups, admins, accounts, and roles from Duo to Baton.",
+		Description: "Connector syncing users, groups, admins, accounts, roles, phones, tokens, WebAuthn credentials, bypass codes, applications, administrative units, and policies from Duo to Baton.",
 	}, nil
 }
 
 // Validate hits the Duo API to validate API credentials.
 func (d *Duo) Validate(ctx context.Context) (annotations.Annotations, error) {
-	_, err := d.client.GetIntegration(ctx)
+	if d.accounts.childAccounts {
+		_, annos, err := d.client.GetChildAccounts(ctx)
+		if err != nil {
+			return annos, wrapError(err, "error fetching child accounts by credentials")
+		}
+		return annos, nil
+	}
+
+	_, annos, err := d.client.GetIntegration(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching integration by credentials: %w", err)
+		return annos, wrapError(err, "error fetching integration by credentials")
 	}
-	return nil, nil
+	return annos, nil
 }
 
 // New returns the Duo connector.
-func New(ctx context.Context, integrationKey string, secretKey string, apiHostname string) (*Duo, error) {
+func New(
+	ctx context.Context,
+	integrationKey string,
+	secretKey string,
+	apiHostname string,
+	adminFallbackRole string,
+	telephonyUsageDays int,
+	childAccounts bool,
+	bypassCodeParams duo.BypassCodeParams,
+) (*Duo, error) {
 	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
 	if err != nil {
 		return nil, err
 	}
 
+	client := duo.NewClient(integrationKey, secretKey, apiHostname, httpClient)
 	return &Duo{
-		client:         duo.NewClient(integrationKey, secretKey, apiHostname, httpClient),
-		integrationKey: integrationKey,
+		client:             client,
+		accounts:           newAccountClients(client, childAccounts),
+		integrationKey:     integrationKey,
+		adminFallbackRole:  adminFallbackRole,
+		telephonyUsageDays: telephonyUsageDays,
+		bypassCodeParams:   bypassCodeParams,
+		users:              newResourceCache(),
+		groups:             newResourceCache(),
+		admins:             newResourceCache(),
+		disabledUsers:      newGrantCache(),
 	}, nil
 }
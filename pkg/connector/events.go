@@ -0,0 +1,297 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// Duo makes log entries available about two minutes after they happen.
+	logAvailabilityDelay = 2 * time.Minute
+	// How far back to read when the SDK doesn't ask for a starting point.
+	defaultEventLookback = 24 * time.Hour
+
+	authEventType     = "authentication"
+	authResultSuccess = "success"
+
+	trustMonitorTypeBypassStatus = "bypass_status"
+)
+
+// eventCursor is the stream cursor handed back to the SDK between ListEvents
+// calls. Each Duo log is read independently and has its own position.
+type eventCursor struct {
+	Authentication logCursor `json:"authentication"`
+	TrustMonitor   logCursor `json:"trust_monitor"`
+}
+
+// logCursor tracks the time window currently being read from a Duo log.
+type logCursor struct {
+	MinTime    int64  `json:"min_time"`
+	MaxTime    int64  `json:"max_time"`
+	NextOffset string `json:"next_offset,omitempty"`
+}
+
+func parseEventCursor(token string) (*eventCursor, error) {
+	cursor := &eventCursor{}
+	if token == "" {
+		return cursor, nil
+	}
+
+	if err := json.Unmarshal([]byte(token), cursor); err != nil {
+		return nil, fmt.Errorf("duo-connector: invalid event cursor: %w", err)
+	}
+
+	return cursor, nil
+}
+
+func (c *eventCursor) marshal() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// next returns the query for the next page of the log. Once a window is
+// exhausted a new one is opened, starting right after it and ending at until.
+// It returns false when there is no new window to read yet.
+func (c *logCursor) next(earliest time.Time, until time.Time, limit int) (duo.LogQuery, bool) {
+	if c.NextOffset == "" {
+		minTime := earliest.UnixMilli()
+		if c.MaxTime != 0 {
+			minTime = c.MaxTime + 1
+		}
+		maxTime := until.UnixMilli()
+		if minTime > maxTime {
+			return duo.LogQuery{}, false
+		}
+		c.MinTime, c.MaxTime = minTime, maxTime
+	}
+
+	return duo.LogQuery{
+		MinTime:    c.MinTime,
+		MaxTime:    c.MaxTime,
+		NextOffset: c.NextOffset,
+		Limit:      limit,
+	}, true
+}
+
+// ListEvents streams Duo log entries as events:
+//   - successful authentications, as usage of the application by the user.
+//   - Trust Monitor risk signals, as usage of the affected user, with the
+//     details of the signal attached as an annotation.
+func (d *Duo) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	// An Accounts API integration can't read the logs of its child accounts.
+	if d.accounts.childAccounts {
+		return nil, &pagination.StreamState{Cursor: pToken.Cursor}, nil, nil
+	}
+
+	cursor, err := parseEventCursor(pToken.Cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	now := time.Now()
+	until := now.Add(-logAvailabilityDelay)
+	earliest := now.Add(-defaultEventLookback)
+	if earliestEvent != nil && earliestEvent.IsValid() {
+		earliest = earliestEvent.AsTime()
+	}
+
+	events, hasMore, annos, err := d.listAuthenticationEvents(ctx, &cursor.Authentication, earliest, until, pToken.Size)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	trustEvents, trustHasMore, trustAnnos, err := d.listTrustMonitorEvents(ctx, &cursor.TrustMonitor, earliest, until, pToken.Size)
+	annos = append(annos, trustAnnos...)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+	events = append(events, trustEvents...)
+	hasMore = hasMore || trustHasMore
+
+	nextCursor, err := cursor.marshal()
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	return events, &pagination.StreamState{Cursor: nextCursor, HasMore: hasMore}, annos, nil
+}
+
+func (d *Duo) listAuthenticationEvents(
+	ctx context.Context,
+	cursor *logCursor,
+	earliest time.Time,
+	until time.Time,
+	limit int,
+) ([]*v2.Event, bool, annotations.Annotations, error) {
+	query, ok := cursor.next(earliest, until, limit)
+	if !ok {
+		return nil, false, nil, nil
+	}
+
+	logs, nextOffset, annos, err := d.client.GetAuthenticationLogs(ctx, query)
+	if err != nil {
+		return nil, false, annos, wrapError(err, "duo-connector: failed to list authentication logs")
+	}
+	cursor.NextOffset = nextOffset
+
+	var rv []*v2.Event
+	for _, log := range logs {
+		if log.EventType != authEventType || log.Result != authResultSuccess {
+			continue
+		}
+
+		rv = append(rv, &v2.Event{
+			Id:         log.TxID,
+			OccurredAt: timestamppb.New(time.Unix(log.Timestamp, 0)),
+			Event: &v2.Event_UsageEvent{
+				UsageEvent: &v2.UsageEvent{
+					TargetResource: applicationEventResource(log.Application.Key, log.Application.Name),
+					ActorResource:  userEventResource(d.users, log.User.Key, log.User.Name),
+				},
+			},
+		})
+	}
+
+	return rv, nextOffset != "", annos, nil
+}
+
+func (d *Duo) listTrustMonitorEvents(
+	ctx context.Context,
+	cursor *logCursor,
+	earliest time.Time,
+	until time.Time,
+	limit int,
+) ([]*v2.Event, bool, annotations.Annotations, error) {
+	query, ok := cursor.next(earliest, until, limit)
+	if !ok {
+		return nil, false, nil, nil
+	}
+
+	trustEvents, nextOffset, annos, err := d.client.GetTrustMonitorEvents(ctx, query)
+	if err != nil {
+		return nil, false, annos, wrapError(err, "duo-connector: failed to list trust monitor events")
+	}
+	cursor.NextOffset = nextOffset
+
+	var rv []*v2.Event
+	for _, trustEvent := range trustEvents {
+		eventCopy := trustEvent
+		event, err := d.trustMonitorEvent(&eventCopy)
+		if err != nil {
+			return nil, false, annos, err
+		}
+		if event != nil {
+			rv = append(rv, event)
+		}
+	}
+
+	return rv, nextOffset != "", annos, nil
+}
+
+// trustMonitorEvent translates a Trust Monitor event into an event on the user
+// it concerns. A surfaced authentication carries the application it was made
+// to, and a bypass status change the admin who made it.
+func (d *Duo) trustMonitorEvent(trustEvent *duo.TrustMonitorEvent) (*v2.Event, error) {
+	var target, actor *v2.Resource
+	var annos annotations.Annotations
+
+	switch {
+	case trustEvent.SurfacedAuth != nil && trustEvent.SurfacedAuth.User.Key != "":
+		auth := trustEvent.SurfacedAuth
+		target = userEventResource(d.users, auth.User.Key, auth.User.Name)
+		if auth.Application.Key != "" {
+			annos.Append(applicationEventResource(auth.Application.Key, auth.Application.Name))
+		}
+	case trustEvent.Type == trustMonitorTypeBypassStatus && trustEvent.EnabledFor != nil:
+		target = userEventResource(d.users, trustEvent.EnabledFor.Key, trustEvent.EnabledFor.Name)
+		if trustEvent.EnabledBy != nil {
+			if ar, ok := d.admins.get(trustEvent.EnabledBy.Key); ok {
+				actor = ar
+			}
+		}
+	default:
+		return nil, nil
+	}
+
+	details, err := trustMonitorDetails(trustEvent)
+	if err != nil {
+		return nil, err
+	}
+	annos.Append(details)
+
+	return &v2.Event{
+		Id:         trustEvent.SEKey,
+		OccurredAt: timestamppb.New(time.UnixMilli(trustEvent.SurfacedTimestamp)),
+		Event: &v2.Event_UsageEvent{
+			UsageEvent: &v2.UsageEvent{
+				TargetResource: target,
+				ActorResource:  actor,
+			},
+		},
+		Annotations: annos,
+	}, nil
+}
+
+// trustMonitorDetails describes why Trust Monitor surfaced an event.
+func trustMonitorDetails(trustEvent *duo.TrustMonitorEvent) (*structpb.Struct, error) {
+	explanations := make([]interface{}, 0, len(trustEvent.Explanations))
+	for _, explanation := range trustEvent.Explanations {
+		explanations = append(explanations, explanation.Summary)
+	}
+
+	priorityReasons := make([]interface{}, 0, len(trustEvent.PriorityReasons))
+	for _, reason := range trustEvent.PriorityReasons {
+		priorityReasons = append(priorityReasons, reason.Label)
+	}
+
+	details, err := structpb.NewStruct(map[string]interface{}{
+		"source":           "trust_monitor",
+		"type":             trustEvent.Type,
+		"state":            trustEvent.State,
+		"priority_event":   trustEvent.PriorityEvent,
+		"priority_reasons": priorityReasons,
+		"explanations":     explanations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("duo-connector: failed to describe trust monitor event %s: %w", trustEvent.SEKey, err)
+	}
+
+	return details, nil
+}
+
+// userEventResource references a user in an event, preferring the synced resource.
+func userEventResource(users *resourceCache, userId string, name string) *v2.Resource {
+	if ur, ok := users.get(userId); ok {
+		return ur
+	}
+
+	return &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: userId},
+		DisplayName: name,
+	}
+}
+
+// applicationEventResource references a Duo protected application by its integration key.
+func applicationEventResource(integrationKey string, name string) *v2.Resource {
+	return &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceTypeApplication.Id, Resource: integrationKey},
+		DisplayName: name,
+	}
+}
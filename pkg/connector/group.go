@@ -10,27 +10,39 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 )
 
-const memberEntitlement = "member"
+const (
+	memberEntitlement = "member"
+
+	groupStatusActive   = "active"
+	groupStatusBypass   = "bypass"
+	groupStatusDisabled = "disabled"
+)
 
 type groupResourceType struct {
-	resourceType *v2.ResourceType
-	client       *duo.Client
+	resourceType   *v2.ResourceType
+	accounts       *accountClients
+	integrationKey string
+	groups         *resourceCache
 }
 
 func (o *groupResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 	return o.resourceType
 }
 
-func groupBuilder(client *duo.Client) *groupResourceType {
+func groupBuilder(accounts *accountClients, integrationKey string, groups *resourceCache) *groupResourceType {
 	return &groupResourceType{
-		resourceType: resourceTypeGroup,
-		client:       client,
+		resourceType:   resourceTypeGroup,
+		accounts:       accounts,
+		integrationKey: integrationKey,
+		groups:         groups,
 	}
 }
 
@@ -39,6 +51,8 @@ func groupResource(ctx context.Context, group duo.Group, parentResourceID *v2.Re
 	profile := make(map[string]interface{})
 	profile["group_id"] = group.GroupID
 	profile["group_name"] = group.Name
+	profile["desc"] = group.Desc
+	profile["status"] = group.Status
 
 	groupTrait := []rs.GroupTraitOption{
 		rs.WithGroupProfile(profile),
@@ -50,6 +64,7 @@ func groupResource(ctx context.Context, group duo.Group, parentResourceID *v2.Re
 		group.GroupID,
 		groupTrait,
 		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(group.Desc),
 	)
 
 	if err != nil {
@@ -70,9 +85,15 @@ func (o *groupResourceType) List(ctx context.Context, parentId *v2.ResourceId, t
 		return nil, "", nil, err
 	}
 
-	groups, offset, err := o.client.GetGroups(ctx, bag.PageToken())
+	client, annos, err := o.accounts.forParent(ctx, parentId)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", annos, err
+	}
+
+	groups, offset, listAnnos, err := client.GetGroups(ctx, bag.PageToken())
+	annos = append(annos, listAnnos...)
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list groups")
 	}
 
 	if offset != "" {
@@ -88,10 +109,11 @@ func (o *groupResourceType) List(ctx context.Context, parentId *v2.ResourceId, t
 		if err != nil {
 			return nil, "", nil, err
 		}
+		o.groups.set(gr)
 		rv = append(rv, gr)
 	}
 
-	return rv, pageToken, nil, nil
+	return rv, pageToken, annos, nil
 }
 
 func (o *groupResourceType) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -116,9 +138,15 @@ func (o *groupResourceType) Grants(ctx context.Context, resource *v2.Resource, t
 		return nil, "", nil, err
 	}
 
-	users, offset, err := o.client.GetGroupUsers(ctx, resource.Id.Resource, bag.PageToken())
+	client, annos, err := o.accounts.forParent(ctx, resource.ParentResourceId)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", annos, err
+	}
+
+	users, offset, listAnnos, err := client.GetGroupUsers(ctx, resource.Id.Resource, bag.PageToken())
+	annos = append(annos, listAnnos...)
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list group members")
 	}
 
 	if offset != "" {
@@ -128,23 +156,20 @@ func (o *groupResourceType) Grants(ctx context.Context, resource *v2.Resource, t
 		}
 	}
 
+	// Grants only need the member's user ID, which the group users payload
+	// already has, so members aren't fetched one by one.
 	var rv []*v2.Grant
 	for _, user := range users {
-		userCopy := user
-		user, err := o.client.GetUser(ctx, userCopy.UserID)
-		if err != nil {
-			return nil, "", nil, err
-		}
-		ur, err := userResource(ctx, &user, resource.Id)
+		principal, err := rs.NewResourceID(resourceTypeUser, user.UserID)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
-		membershipGrant := grant.NewGrant(resource, memberEntitlement, ur.Id)
+		membershipGrant := grant.NewGrant(resource, memberEntitlement, principal)
 		rv = append(rv, membershipGrant)
 	}
 
-	return rv, pageToken, nil, nil
+	return rv, pageToken, annos, nil
 }
 
 func (o *groupResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
@@ -159,12 +184,18 @@ func (o *groupResourceType) Grant(ctx context.Context, principal *v2.Resource, e
 		return nil, fmt.Errorf("baton-duo: only users can be granted group membership")
 	}
 
-	err := o.client.AddUserToGroup(ctx, entitlement.Resource.Id.Resource, principal.Id.Resource)
+	client, annos, err := o.accounts.forParent(ctx, entitlement.Resource.ParentResourceId)
+	if err != nil {
+		return annos, err
+	}
+
+	addAnnos, err := client.AddUserToGroup(ctx, entitlement.Resource.Id.Resource, principal.Id.Resource)
+	annos = append(annos, addAnnos...)
 	if err != nil {
-		return nil, fmt.Errorf("baton-duo: error granting group membership: %w", err)
+		return annos, wrapError(err, "baton-duo: error granting group membership")
 	}
 
-	return nil, nil
+	return annos, nil
 }
 
 func (o *groupResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
@@ -182,10 +213,100 @@ func (o *groupResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annota
 		return nil, fmt.Errorf("baton-duo: only users can have group membership revoked")
 	}
 
-	err := o.client.RemoveUserFromGroup(ctx, entitlement.Resource.Id.Resource, principal.Id.Resource)
+	client, annos, err := o.accounts.forParent(ctx, entitlement.Resource.ParentResourceId)
 	if err != nil {
-		return nil, fmt.Errorf("baton-duo: error revoking group membership: %w", err)
+		return annos, err
+	}
+
+	removeAnnos, err := client.RemoveUserFromGroup(ctx, entitlement.Resource.Id.Resource, principal.Id.Resource)
+	annos = append(annos, removeAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error revoking group membership")
+	}
+
+	return annos, nil
+}
+
+// Create creates a Duo group from the resource's display name and description,
+// in the account the resource's parent is. Without child accounts the parent
+// defaults to the account. The group profile's status sets the group status,
+// "active" by default, and is changed later through the account's "disabled" and
+// "bypass" entitlements. Renaming a group is not supported: the SDK has no hook
+// for updating a resource.
+func (o *groupResourceType) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	params := duo.GroupParams{
+		Name: resource.GetDisplayName(),
+		Desc: resource.GetDescription(),
+	}
+	if params.Name == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "baton-duo: a name is required to create a group")
 	}
 
-	return nil, nil
+	groupTrait, err := rs.GetGroupTrait(resource)
+	if err == nil {
+		params.Status, _ = rs.GetProfileStringValue(groupTrait.GetProfile(), "status")
+	}
+	if err := validateGroupStatus(params.Status); err != nil {
+		return nil, nil, err
+	}
+
+	parentId := resource.GetParentResourceId()
+	if parentId.GetResourceType() != resourceTypeAccount.Id {
+		if o.accounts.childAccounts {
+			return nil, nil, status.Error(codes.InvalidArgument, "baton-duo: a child account parent is required to create a group in a child account")
+		}
+
+		parentId, err = rs.NewResourceID(resourceTypeAccount, o.integrationKey)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	client, annos, err := o.accounts.forParent(ctx, parentId)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	group, createAnnos, err := client.CreateGroup(ctx, params)
+	annos = append(annos, createAnnos...)
+	if err != nil {
+		return nil, annos, wrapError(err, "baton-duo: error creating group")
+	}
+
+	gr, err := groupResource(ctx, group, parentId)
+	if err != nil {
+		return nil, annos, err
+	}
+	o.groups.set(gr)
+
+	return gr, annos, nil
+}
+
+// Delete removes a Duo group. Its members are kept.
+func (o *groupResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != resourceTypeGroup.Id {
+		return nil, fmt.Errorf("baton-duo: only groups can be deleted by this resource type")
+	}
+
+	client, annos, err := o.accounts.forResource(ctx, o.groups, resourceId, lookupGroup)
+	if err != nil {
+		return annos, err
+	}
+
+	deleteAnnos, err := client.DeleteGroup(ctx, resourceId.Resource)
+	annos = append(annos, deleteAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error deleting group")
+	}
+
+	return annos, nil
+}
+
+func validateGroupStatus(groupStatus string) error {
+	switch groupStatus {
+	case "", groupStatusActive, groupStatusBypass, groupStatusDisabled:
+		return nil
+	default:
+		return status.Errorf(codes.InvalidArgument, "baton-duo: unknown group status %q, expected active, bypass or disabled", groupStatus)
+	}
 }
@@ -1,19 +1,19 @@
 package connector
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-func titleCase(s string) string {
-	titleCaser := cases.Title(language.English)
-
-	return titleCaser.String(s)
-}
-
 func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, error) {
 	b := &pagination.Bag{}
 	err := b.Unmarshal(i)
@@ -31,8 +31,86 @@ func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, error
 	return b, nil
 }
 
+// entitlementSlug returns the entitlement's slug. Entitlement IDs end with the
+// slug, which isn't always set on requests.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	if entitlement.Slug != "" {
+		return entitlement.Slug
+	}
+
+	return entitlement.Id[strings.LastIndex(entitlement.Id, ":")+1:]
+}
+
+// describe renders key/value pairs as a resource description, skipping empty
+// values. Resource types without a trait have no profile, so this is where
+// their attributes go.
+func describe(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", pairs[i], pairs[i+1]))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
 func annotationsForUserResourceType() annotations.Annotations {
 	annos := annotations.Annotations{}
 	annos.Update(&v2.SkipEntitlementsAndGrants{})
 	return annos
 }
+
+// wrapError attaches a gRPC status code matching the Duo API error, so that e.g. a
+// credential missing the "Grant read resource" permission is distinguishable from
+// a user that doesn't exist. The cause stays reachable through errors.Is and errors.As.
+func wrapError(err error, message string) error {
+	return &statusError{
+		code:    errorCode(err),
+		message: fmt.Sprintf("%s: %s", message, err.Error()),
+		cause:   err,
+	}
+}
+
+// statusError is an error carrying a gRPC status that still unwraps to its cause.
+type statusError struct {
+	code    codes.Code
+	message string
+	cause   error
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return status.New(e.code, e.message)
+}
+
+func (e *statusError) Unwrap() error {
+	return e.cause
+}
+
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case duo.IsNotFound(err):
+		return codes.NotFound
+	case duo.IsUnauthorized(err):
+		return codes.Unauthenticated
+	case duo.IsPermissionDenied(err):
+		return codes.PermissionDenied
+	case duo.IsRateLimited(err):
+		return codes.Unavailable
+	case duo.IsInvalidRequest(err):
+		return codes.InvalidArgument
+	case duo.IsServerError(err):
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
@@ -0,0 +1,137 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testRoute answers a request to one Duo API path with the response payload,
+// and the next offset when the payload is a page of a list.
+type testRoute func(r *http.Request) (response interface{}, nextOffset string)
+
+// respond answers every request with the same payload.
+func respond(response interface{}) testRoute {
+	return func(*http.Request) (interface{}, string) {
+		return response, ""
+	}
+}
+
+// testDuo serves Duo API routes from a TLS test server and counts the requests
+// made to each path.
+type testDuo struct {
+	client *duo.Client
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newTestDuo(t *testing.T, routes map[string]testRoute) *testDuo {
+	t.Helper()
+
+	d := &testDuo{requests: make(map[string]int)}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		d.requests[r.URL.Path]++
+		d.mu.Unlock()
+
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"stat": "FAIL", "code": 40401, "message": "Resource not found"})
+			return
+		}
+
+		response, nextOffset := route(r)
+		body := map[string]interface{}{"stat": "OK", "response": response}
+		if nextOffset != "" {
+			body["metadata"] = map[string]interface{}{"next_offset": json.Number(nextOffset)}
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+
+	serverUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.client = duo.NewClient("DIXXXXXXXXXXXXXXXXXX", "secret", serverUrl.Host, server.Client())
+
+	return d
+}
+
+// count returns the number of requests made to a path.
+func (d *testDuo) count(path string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.requests[path]
+}
+
+// testAccount is the account resource the other resource types are listed under.
+var testAccount = &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeAccount.Id, Resource: testIntegrationKey}}
+
+// grantPrincipals returns the principal IDs of a resource's grants, which all
+// fit on one page.
+func grantPrincipals(t *testing.T, syncer connectorbuilder.ResourceSyncer, resource *v2.Resource) []string {
+	t.Helper()
+
+	grants, next, _, err := syncer.Grants(context.Background(), resource, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("Grants() error = %v", err)
+	}
+	if next != "" {
+		t.Fatalf("Grants() returned next page %q; want a single page", next)
+	}
+
+	var rv []string
+	for _, g := range grants {
+		rv = append(rv, g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource)
+	}
+
+	return rv
+}
+
+func TestWrapError(t *testing.T) {
+	notFound := &duo.APIError{StatusCode: http.StatusNotFound, Code: 40401, Message: "Resource not found"}
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+	}{
+		{name: "api error", err: fmt.Errorf("error fetching user: %w", notFound), wantCode: codes.NotFound},
+		{name: "canceled", err: fmt.Errorf("error fetching users: %w", context.Canceled), wantCode: codes.Canceled},
+		{name: "deadline exceeded", err: fmt.Errorf("error fetching users: %w", context.DeadlineExceeded), wantCode: codes.DeadlineExceeded},
+		{name: "other", err: errors.New("connection reset by peer"), wantCode: codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError(tt.err, "baton-duo: failed to list users")
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("status.Code() = %v; want %v", got, tt.wantCode)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("errors.Is(wrapped, cause) = false; want true")
+			}
+			if want := "baton-duo: failed to list users: " + tt.err.Error(); err.Error() != want {
+				t.Errorf("Error() = %q; want %q", err.Error(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	ownerEntitlement = "owner"
+
+	telephonyTypeSMS   = "sms"
+	telephonyTypePhone = "phone"
+)
+
+type phoneResourceType struct {
+	resourceType *v2.ResourceType
+	client       *duo.Client
+	owners       *grantCache
+
+	// When telephonyUsageDays is set, telephony credits consumed over that many
+	// days are aggregated once per sync and reported on each phone.
+	telephonyUsageDays int
+	usageMu            sync.Mutex
+	telephonyUsage     map[string]*telephonyUsage
+}
+
+// telephonyUsage is the telephony consumption attributed to one phone number.
+type telephonyUsage struct {
+	credits int64
+	sms     int64
+	calls   int64
+}
+
+func (o *phoneResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func phoneDisplayName(phone *duo.Phone) string {
+	switch {
+	case phone.Number != "":
+		return phone.Number
+	case phone.Name != "":
+		return phone.Name
+	case phone.Model != "":
+		return phone.Model
+	default:
+		return phone.PhoneID
+	}
+}
+
+// Create a new connector resource for a Duo phone. Phones have no trait of
+// their own, so the group trait carries their profile.
+func phoneResource(ctx context.Context, phone *duo.Phone, usage *telephonyUsage, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	capabilities := make([]interface{}, 0, len(phone.Capabilities))
+	for _, capability := range phone.Capabilities {
+		capabilities = append(capabilities, capability)
+	}
+
+	profile := map[string]interface{}{
+		"phone_id":           phone.PhoneID,
+		"name":               phone.Name,
+		"number":             phone.Number,
+		"extension":          phone.Extension,
+		"type":               phone.Type,
+		"platform":           phone.Platform,
+		"model":              phone.Model,
+		"activated":          phone.Activated,
+		"sms_passcodes_sent": phone.SMSPasscodesSent,
+		"last_seen":          phone.LastSeen,
+		"capabilities":       capabilities,
+	}
+	if usage != nil {
+		profile["telephony_credits"] = usage.credits
+		profile["sms_sent"] = usage.sms
+		profile["calls"] = usage.calls
+	}
+
+	phoneTraitOptions := []rs.GroupTraitOption{
+		rs.WithGroupProfile(profile),
+	}
+
+	ret, err := rs.NewGroupResource(
+		phoneDisplayName(phone),
+		resourceTypePhone,
+		phone.PhoneID,
+		phoneTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (o *phoneResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	var pageToken string
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypePhone.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	o.owners.startList(bag)
+
+	usageByNumber, annos, err := o.telephonyUsageByNumber(ctx, bag.PageToken() == "")
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	phones, offset, phoneAnnos, err := o.client.GetPhones(ctx, bag.PageToken())
+	annos = append(annos, phoneAnnos...)
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list phones")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Resource
+	for _, phone := range phones {
+		phoneCopy := phone
+		var usage *telephonyUsage
+		if usageByNumber != nil {
+			usage = usageByNumber[phone.Number]
+			if usage == nil {
+				usage = &telephonyUsage{}
+			}
+		}
+
+		pr, err := phoneResource(ctx, &phoneCopy, usage, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		owners, err := phoneOwners(&phoneCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		o.owners.set(phone.PhoneID, owners)
+		rv = append(rv, pr)
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+func (o *phoneResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeUser),
+		ent.WithDescription(fmt.Sprintf("Owner of %s phone in Duo", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Phone %s", resource.DisplayName, ownerEntitlement)),
+	}
+
+	en := ent.NewAssignmentEntitlement(resource, ownerEntitlement, assignmentOptions...)
+	rv = append(rv, en)
+
+	return rv, "", nil, nil
+}
+
+// Grants makes every user a phone is attached to one of its owners, so a
+// phone shared by several users has an owner grant for each of them.
+func (o *phoneResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	rv, annos, err := o.owners.grants(ctx, resource, ownerEntitlement, o.fetchOwners)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return rv, "", annos, nil
+}
+
+// fetchOwners fetches the users of a phone that wasn't listed.
+func (o *phoneResourceType) fetchOwners(ctx context.Context, phoneId string) ([]*v2.ResourceId, annotations.Annotations, error) {
+	phone, annos, err := o.client.GetPhone(ctx, phoneId)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to fetch phone")
+	}
+
+	owners, err := phoneOwners(&phone)
+	return owners, annos, err
+}
+
+// phoneOwners returns the users a phone is attached to.
+func phoneOwners(phone *duo.Phone) ([]*v2.ResourceId, error) {
+	var owners []*v2.ResourceId
+	for _, user := range phone.Users {
+		principal, err := rs.NewResourceID(resourceTypeUser, user.UserID)
+		if err != nil {
+			return nil, err
+		}
+		owners = append(owners, principal)
+	}
+
+	return owners, nil
+}
+
+// telephonyUsageByNumber returns the telephony usage of this sync, loading it
+// again when a new sync starts. It is nil when usage isn't reported.
+func (o *phoneResourceType) telephonyUsageByNumber(ctx context.Context, newSync bool) (map[string]*telephonyUsage, annotations.Annotations, error) {
+	if o.telephonyUsageDays <= 0 {
+		return nil, nil, nil
+	}
+
+	o.usageMu.Lock()
+	defer o.usageMu.Unlock()
+
+	if !newSync && o.telephonyUsage != nil {
+		return o.telephonyUsage, nil, nil
+	}
+
+	usage, annos, err := o.loadTelephonyUsage(ctx)
+	if err != nil {
+		return nil, annos, err
+	}
+	o.telephonyUsage = usage
+
+	return usage, annos, nil
+}
+
+// loadTelephonyUsage aggregates the telephony log over the configured window by phone number.
+func (o *phoneResourceType) loadTelephonyUsage(ctx context.Context) (map[string]*telephonyUsage, annotations.Annotations, error) {
+	until := time.Now().Add(-logAvailabilityDelay)
+	query := duo.LogQuery{
+		MinTime: until.AddDate(0, 0, -o.telephonyUsageDays).UnixMilli(),
+		MaxTime: until.UnixMilli(),
+	}
+
+	var annos annotations.Annotations
+	usage := make(map[string]*telephonyUsage)
+	for {
+		logs, nextOffset, pageAnnos, err := o.client.GetTelephonyLogs(ctx, query)
+		annos = append(annos, pageAnnos...)
+		if err != nil {
+			return nil, annos, wrapError(err, "duo-connector: failed to list telephony logs")
+		}
+
+		for _, log := range logs {
+			u, ok := usage[log.Phone]
+			if !ok {
+				u = &telephonyUsage{}
+				usage[log.Phone] = u
+			}
+
+			u.credits += log.Credits
+			switch log.Type {
+			case telephonyTypeSMS:
+				u.sms++
+			case telephonyTypePhone:
+				u.calls++
+			}
+		}
+
+		if nextOffset == "" {
+			return usage, annos, nil
+		}
+		query.NextOffset = nextOffset
+	}
+}
+
+func phoneBuilder(client *duo.Client, telephonyUsageDays int) *phoneResourceType {
+	return &phoneResourceType{
+		resourceType:       resourceTypePhone,
+		client:             client,
+		owners:             newGrantCache(),
+		telephonyUsageDays: telephonyUsageDays,
+	}
+}
@@ -0,0 +1,65 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+func TestPhoneListAndGrants(t *testing.T) {
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/phones": respond([]duo.Phone{
+			{
+				PhoneID:      "DP1",
+				Number:       "+15555550100",
+				Platform:     "Apple iOS",
+				Activated:    true,
+				Capabilities: []string{"push", "sms"},
+				Users:        []duo.User{{UserID: "U1"}, {UserID: "U2"}},
+			},
+			{PhoneID: "DP2", Number: "+15555550101"},
+		}),
+		"/admin/v1/phones/DP3": respond(duo.Phone{PhoneID: "DP3", Users: []duo.User{{UserID: "U3"}}}),
+	})
+	o := phoneBuilder(d.client, 0)
+
+	phones, _, _, err := o.List(context.Background(), testAccount.Id, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(phones) != 2 {
+		t.Fatalf("List() returned %d phones; want 2", len(phones))
+	}
+
+	trait, err := rs.GetGroupTrait(phones[0])
+	if err != nil {
+		t.Fatalf("GetGroupTrait() error = %v", err)
+	}
+	if platform, _ := rs.GetProfileStringValue(trait.Profile, "platform"); platform != "Apple iOS" {
+		t.Errorf("platform = %q; want %q", platform, "Apple iOS")
+	}
+	if activated := trait.Profile.GetFields()["activated"].GetBoolValue(); !activated {
+		t.Errorf("activated = false; want true")
+	}
+
+	if got, want := grantPrincipals(t, o, phones[0]), []string{"user:U1", "user:U2"}; !slices.Equal(got, want) {
+		t.Errorf("DP1 owners = %v; want %v", got, want)
+	}
+	if got := grantPrincipals(t, o, phones[1]); len(got) != 0 {
+		t.Errorf("DP2 owners = %v; want none", got)
+	}
+
+	// A phone that wasn't listed is fetched on its own.
+	unlisted := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypePhone.Id, Resource: "DP3"}}
+	if got, want := grantPrincipals(t, o, unlisted), []string{"user:U3"}; !slices.Equal(got, want) {
+		t.Errorf("DP3 owners = %v; want %v", got, want)
+	}
+	if got := d.count("/admin/v1/phones/DP1") + d.count("/admin/v1/phones/DP2"); got != 0 {
+		t.Errorf("listed phones were fetched %d times; want 0", got)
+	}
+}
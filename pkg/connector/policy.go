@@ -0,0 +1,346 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const appliedEntitlement = "applied"
+
+type policyResourceType struct {
+	resourceType *v2.ResourceType
+	client       *duo.Client
+
+	// Policies are applied on integrations, so which applications and groups
+	// each policy covers is gathered once per sync from the integrations.
+	// Integrations without a policy of their own use the global policy, whose
+	// key is recorded while listing.
+	mu              sync.Mutex
+	bindings        *policyBindings
+	globalPolicyKey string
+}
+
+// policyBindings is what each policy applies to, by policy key.
+type policyBindings struct {
+	byPolicy map[string]*policyBinding
+	// integrationNames names the integrations in group policy entitlements.
+	integrationNames map[string]string
+}
+
+// policyBinding is what a policy applies to: whole integrations, and groups
+// within an integration, by integration key.
+type policyBinding struct {
+	integrationKeys []string
+	groupIds        map[string][]string
+}
+
+// groupPolicyEntitlement is the entitlement of groups a policy is applied to
+// within one integration, so the grant says which integration it comes from.
+func groupPolicyEntitlement(integrationKey string) string {
+	return fmt.Sprintf("%s_%s", appliedEntitlement, integrationKey)
+}
+
+func (o *policyResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func policyBuilder(client *duo.Client) *policyResourceType {
+	return &policyResourceType{
+		resourceType: resourceTypePolicy,
+		client:       client,
+	}
+}
+
+// Create a new connector resource for a Duo policy, describing the settings
+// that matter most when reviewing how strongly it protects users.
+func policyResource(ctx context.Context, policy *duo.Policy, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	description := describe(
+		"global", strconv.FormatBool(policy.IsGlobalPolicy),
+		"allowed_methods", strings.Join(policySetting(policy, "authentication_methods", "allowed_auth_list"), " "),
+		"new_user_behavior", strings.Join(policySetting(policy, "new_user", "new_user_behavior"), " "),
+		"remembered_devices", strconv.FormatBool(policyRemembersDevices(policy)),
+	)
+
+	ret, err := rs.NewResource(
+		policy.PolicyName,
+		resourceTypePolicy,
+		policy.PolicyKey,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// policySetting returns a setting of a policy section as strings. Settings not
+// set on the policy are inherited from the global policy and come back empty.
+func policySetting(policy *duo.Policy, section string, setting string) []string {
+	switch value := policy.Sections[section][setting].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var rv []string
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				rv = append(rv, s)
+			}
+		}
+		sort.Strings(rv)
+		return rv
+	default:
+		return nil
+	}
+}
+
+// policyRemembersDevices reports whether the policy lets any kind of
+// application skip authentication on a remembered device.
+func policyRemembersDevices(policy *duo.Policy) bool {
+	for _, v := range policy.Sections["remembered_devices"] {
+		if settings, ok := v.(map[string]interface{}); ok && settings["enabled"] == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *policyResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	var pageToken string
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypePolicy.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// A new sync starts, so integrations may have changed since the last one.
+	if bag.PageToken() == "" {
+		o.mu.Lock()
+		o.bindings = nil
+		o.globalPolicyKey = ""
+		o.mu.Unlock()
+	}
+
+	policies, offset, annos, err := o.client.GetPolicies(ctx, bag.PageToken())
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list policies")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Resource
+	for _, policy := range policies {
+		policyCopy := policy
+		pr, err := policyResource(ctx, &policyCopy, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if policy.IsGlobalPolicy {
+			o.mu.Lock()
+			o.globalPolicyKey = policy.PolicyKey
+			o.mu.Unlock()
+		}
+		rv = append(rv, pr)
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+// Entitlements returns the "applied" entitlement for the applications the policy
+// protects, and one per application whose groups the policy is applied to.
+func (o *policyResourceType) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	bindings, annos, err := o.loadedBindings(ctx)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeApplication),
+		ent.WithDescription(fmt.Sprintf("Protected by the %s policy in Duo", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Policy %s", resource.DisplayName, appliedEntitlement)),
+	}
+
+	en := ent.NewAssignmentEntitlement(resource, appliedEntitlement, assignmentOptions...)
+	rv = append(rv, en)
+
+	binding, ok := bindings.byPolicy[resource.Id.Resource]
+	if !ok {
+		return rv, "", annos, nil
+	}
+
+	for _, integrationKey := range binding.groupIntegrationKeys() {
+		integrationName := bindings.integrationNames[integrationKey]
+		groupOptions := []ent.EntitlementOption{
+			ent.WithGrantableTo(resourceTypeGroup),
+			ent.WithDescription(fmt.Sprintf("Group protected by the %s policy in the %s application in Duo", resource.DisplayName, integrationName)),
+			ent.WithDisplayName(fmt.Sprintf("%s Policy %s in %s", resource.DisplayName, appliedEntitlement, integrationName)),
+		}
+
+		en := ent.NewAssignmentEntitlement(resource, groupPolicyEntitlement(integrationKey), groupOptions...)
+		rv = append(rv, en)
+	}
+
+	return rv, "", annos, nil
+}
+
+// Grants applies the policy to the applications it protects, and to the groups
+// it is applied to within an application, through that application's entitlement.
+func (o *policyResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	bindings, annos, err := o.loadedBindings(ctx)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	binding, ok := bindings.byPolicy[resource.Id.Resource]
+	if !ok {
+		return nil, "", annos, nil
+	}
+
+	var rv []*v2.Grant
+	for _, integrationKey := range binding.integrationKeys {
+		principal, err := rs.NewResourceID(resourceTypeApplication, integrationKey)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, grant.NewGrant(resource, appliedEntitlement, principal))
+	}
+
+	for _, integrationKey := range binding.groupIntegrationKeys() {
+		for _, groupId := range binding.groupIds[integrationKey] {
+			principal, err := rs.NewResourceID(resourceTypeGroup, groupId)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			rv = append(rv, grant.NewGrant(resource, groupPolicyEntitlement(integrationKey), principal))
+		}
+	}
+
+	return rv, "", annos, nil
+}
+
+// groupIntegrationKeys returns the integrations the policy is applied to groups of, sorted.
+func (b *policyBinding) groupIntegrationKeys() []string {
+	keys := make([]string, 0, len(b.groupIds))
+	for integrationKey := range b.groupIds {
+		keys = append(keys, integrationKey)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// loadedBindings returns the bindings of this sync, loading them on first use.
+func (o *policyResourceType) loadedBindings(ctx context.Context) (*policyBindings, annotations.Annotations, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.bindings != nil {
+		return o.bindings, nil, nil
+	}
+
+	bindings, annos, err := o.loadBindings(ctx)
+	if err != nil {
+		return nil, annos, err
+	}
+	o.bindings = bindings
+
+	return bindings, annos, nil
+}
+
+// loadBindings maps each policy to the integrations and groups it is applied
+// to. Integrations without a policy of their own fall back to the global policy,
+// which is only fetched when this process didn't list it. The caller holds o.mu.
+func (o *policyResourceType) loadBindings(ctx context.Context) (*policyBindings, annotations.Annotations, error) {
+	var annos annotations.Annotations
+	globalPolicyKey := o.globalPolicyKey
+	if globalPolicyKey == "" {
+		globalPolicy, globalAnnos, err := o.client.GetGlobalPolicy(ctx)
+		annos = globalAnnos
+		if err != nil {
+			return nil, annos, wrapError(err, "duo-connector: failed to fetch global policy")
+		}
+		globalPolicyKey = globalPolicy.PolicyKey
+		o.globalPolicyKey = globalPolicyKey
+	}
+
+	bindings := &policyBindings{
+		byPolicy:         make(map[string]*policyBinding),
+		integrationNames: make(map[string]string),
+	}
+	binding := func(policyKey string) *policyBinding {
+		b, ok := bindings.byPolicy[policyKey]
+		if !ok {
+			b = &policyBinding{groupIds: make(map[string][]string)}
+			bindings.byPolicy[policyKey] = b
+		}
+		return b
+	}
+
+	var offset string
+	for {
+		integrations, nextOffset, pageAnnos, err := o.client.GetIntegrations(ctx, offset)
+		annos = append(annos, pageAnnos...)
+		if err != nil {
+			return nil, annos, wrapError(err, "duo-connector: failed to list integrations")
+		}
+
+		for _, integration := range integrations {
+			bindings.integrationNames[integration.IntegrationKey] = integration.Name
+
+			policyKey := integration.PolicyKey
+			if policyKey == "" {
+				policyKey = globalPolicyKey
+			}
+			if policyKey != "" {
+				b := binding(policyKey)
+				b.integrationKeys = append(b.integrationKeys, integration.IntegrationKey)
+			}
+
+			for _, groupPolicy := range integration.GroupPolicies {
+				b := binding(groupPolicy.PolicyKey)
+				b.groupIds[integration.IntegrationKey] = append(b.groupIds[integration.IntegrationKey], groupPolicy.GroupIDs...)
+			}
+		}
+
+		if nextOffset == "" {
+			break
+		}
+		offset = nextOffset
+	}
+
+	// An integration can list a group in several of its group policies.
+	for _, b := range bindings.byPolicy {
+		for integrationKey, groupIds := range b.groupIds {
+			slices.Sort(groupIds)
+			b.groupIds[integrationKey] = slices.Compact(groupIds)
+		}
+	}
+
+	return bindings, annos, nil
+}
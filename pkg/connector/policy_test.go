@@ -0,0 +1,69 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func policyTestRoutes() map[string]testRoute {
+	return map[string]testRoute{
+		"/admin/v2/policies": respond([]duo.Policy{
+			{PolicyKey: "POGLOBAL", PolicyName: "Global Policy", IsGlobalPolicy: true},
+			{PolicyKey: "POSTRICT", PolicyName: "Strict"},
+		}),
+		"/admin/v2/policies/global": respond(duo.Policy{PolicyKey: "POGLOBAL", PolicyName: "Global Policy", IsGlobalPolicy: true}),
+		"/admin/v1/integrations": respond([]duo.Integration{
+			{IntegrationKey: "DI1", Name: "VPN"},
+			{
+				IntegrationKey: "DI2",
+				Name:           "SSO",
+				PolicyKey:      "POSTRICT",
+				GroupPolicies:  []duo.IntegrationGroupPolicy{{PolicyKey: "POGLOBAL", GroupIDs: []string{"G1"}}},
+			},
+		}),
+	}
+}
+
+func TestPolicyGrantsFromListedGlobalPolicy(t *testing.T) {
+	d := newTestDuo(t, policyTestRoutes())
+	o := policyBuilder(d.client)
+
+	policies, _, _, err := o.List(context.Background(), testAccount.Id, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(policies) != 2 {
+		t.Fatalf("List() returned %d policies; want 2", len(policies))
+	}
+
+	if got, want := grantPrincipals(t, o, policies[0]), []string{"application:DI1", "group:G1"}; !slices.Equal(got, want) {
+		t.Errorf("global policy grants = %v; want %v", got, want)
+	}
+	if got, want := grantPrincipals(t, o, policies[1]), []string{"application:DI2"}; !slices.Equal(got, want) {
+		t.Errorf("strict policy grants = %v; want %v", got, want)
+	}
+	if got := d.count("/admin/v2/policies"); got != 1 {
+		t.Errorf("policies were listed %d times; want 1, only by List", got)
+	}
+	if got := d.count("/admin/v2/policies/global"); got != 0 {
+		t.Errorf("global policy was fetched %d times; want 0", got)
+	}
+}
+
+func TestPolicyGrantsWithoutListedPolicies(t *testing.T) {
+	d := newTestDuo(t, policyTestRoutes())
+	o := policyBuilder(d.client)
+
+	global := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypePolicy.Id, Resource: "POGLOBAL"}}
+	if got, want := grantPrincipals(t, o, global), []string{"application:DI1", "group:G1"}; !slices.Equal(got, want) {
+		t.Errorf("global policy grants = %v; want %v", got, want)
+	}
+	if got := d.count("/admin/v2/policies/global"); got != 1 {
+		t.Errorf("global policy was fetched %d times; want 1", got)
+	}
+}
@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/conductorone/baton-duo/pkg/duo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -11,45 +12,69 @@ import (
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	resource "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type roleResourceType struct {
 	resourceType *v2.ResourceType
 	client       *duo.Client
+	fallbackRole string
 }
 
 func (o *roleResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 	return o.resourceType
 }
 
-const (
-	owner              = "owner"
-	administrator      = "administrator"
-	applicationManager = "application manager"
-	userManager        = "user manager"
-	helpDesk           = "help desk"
-	billing            = "billing"
-	phishingManager    = "phishing manager"
-	readOnly           = "readonly"
-)
+// builtinRoles are the roles every Duo account has, named as the Admin API
+// reports and expects them.
+var builtinRoles = []string{
+	"Owner",
+	"Administrator",
+	"Application Manager",
+	"User Manager",
+	"Security Analyst",
+	"Help Desk",
+	"Billing",
+	"Phishing Manager",
+	"Read-only",
+}
 
-var roles = []string{
-	owner,
-	administrator,
-	applicationManager,
-	userManager,
-	helpDesk,
-	billing,
-	phishingManager,
-	readOnly,
+// role is a Duo admin role, either built-in or custom. Roles only known from
+// the admins holding them are neither in the built-in list nor reported as
+// custom, so whether they are custom is unknown.
+type role struct {
+	name        string
+	custom      bool
+	fromAdmins  bool
+	permissions []string
+}
+
+// roleId normalizes a role name into the role's resource ID, so names that only
+// differ in case or punctuation match, e.g. "Read-only" and "Readonly".
+func roleId(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "-", ""))
+	return strings.Join(strings.Fields(name), " ")
 }
 
 // Create a new connector resource for a Duo role.
-func roleResource(ctx context.Context, role string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
-	roleDisplayName := titleCase(role)
+func roleResource(ctx context.Context, role *role, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	permissions := make([]interface{}, 0, len(role.permissions))
+	for _, permission := range role.permissions {
+		permissions = append(permissions, permission)
+	}
+
 	profile := map[string]interface{}{
-		"role_name": roleDisplayName,
-		"role_id":   role,
+		"role_name":   role.name,
+		"role_id":     roleId(role.name),
+		"permissions": permissions,
+	}
+	if role.fromAdmins {
+		profile["discovered_from_admins"] = true
+	} else {
+		profile["custom"] = role.custom
 	}
 
 	roleTraitOptions := []resource.RoleTraitOption{
@@ -57,9 +82,9 @@ func roleResource(ctx context.Context, role string, parentResourceID *v2.Resourc
 	}
 
 	ret, err := resource.NewRoleResource(
-		roleDisplayName,
+		role.name,
 		resourceTypeRole,
-		role,
+		roleId(role.name),
 		roleTraitOptions,
 		resource.WithParentResourceID(parentResourceID),
 	)
@@ -70,11 +95,104 @@ func roleResource(ctx context.Context, role string, parentResourceID *v2.Resourc
 	return ret, nil
 }
 
+// listRoles discovers the account's roles: the built-in roles, the custom roles
+// and any other role currently held by an admin, so roles the custom role
+// endpoint doesn't report are still visible.
+func (o *roleResourceType) listRoles(ctx context.Context) ([]*role, annotations.Annotations, error) {
+	var rv []*role
+	seen := make(map[string]bool)
+	add := func(r *role) {
+		if r.name == "" || seen[roleId(r.name)] {
+			return
+		}
+		seen[roleId(r.name)] = true
+		rv = append(rv, r)
+	}
+
+	for _, name := range builtinRoles {
+		add(&role{name: name})
+	}
+
+	customRoles, annos, err := o.customAdminRoles(ctx)
+	if err != nil {
+		return nil, annos, err
+	}
+	for _, customRole := range customRoles {
+		add(&role{name: customRole.Name, custom: true, permissions: customRole.Permissions})
+	}
+
+	var offset string
+	for {
+		admins, nextOffset, adminAnnos, err := o.client.GetAdmins(ctx, offset)
+		annos = append(annos, adminAnnos...)
+		if err != nil {
+			return nil, annos, wrapError(err, "duo-connector: failed to list admins")
+		}
+
+		for _, admin := range admins {
+			add(&role{name: admin.Role, fromAdmins: true})
+		}
+
+		if nextOffset == "" {
+			return rv, annos, nil
+		}
+		offset = nextOffset
+	}
+}
+
+// customAdminRoles returns the account's custom admin roles. Accounts whose
+// edition has no custom roles, or whose API credentials can't read them, only
+// have the built-in roles and the roles held by admins, so those errors are logged.
+func (o *roleResourceType) customAdminRoles(ctx context.Context) ([]duo.AdminRole, annotations.Annotations, error) {
+	customRoles, annos, err := o.client.GetCustomAdminRoles(ctx)
+	if err != nil {
+		if duo.IsNotFound(err) || duo.IsPermissionDenied(err) {
+			l := ctxzap.Extract(ctx)
+			l.Warn("baton-duo: custom admin roles are unavailable, only syncing built-in roles and roles held by admins", zap.Error(err))
+			return nil, annos, nil
+		}
+		return nil, annos, wrapError(err, "baton-duo: error fetching custom admin roles")
+	}
+
+	return customRoles, annos, nil
+}
+
+// roleName returns the name the Admin API expects for a role resource.
+func (o *roleResourceType) roleName(ctx context.Context, roleResource *v2.Resource) (string, annotations.Annotations, error) {
+	id := roleResource.Id.Resource
+	for _, name := range builtinRoles {
+		if roleId(name) == id {
+			return name, nil, nil
+		}
+	}
+
+	customRoles, annos, err := o.customAdminRoles(ctx)
+	if err != nil {
+		return "", annos, err
+	}
+	for _, customRole := range customRoles {
+		if roleId(customRole.Name) == id {
+			return customRole.Name, annos, nil
+		}
+	}
+
+	if roleResource.DisplayName != "" {
+		return roleResource.DisplayName, annos, nil
+	}
+
+	return "", annos, status.Errorf(codes.NotFound, "baton-duo: unknown role %s", id)
+}
+
 func (o *roleResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	if parentId == nil {
 		return nil, "", nil, nil
 	}
 
+	roles, annos, err := o.listRoles(ctx)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
 	var rv []*v2.Resource
 	for _, role := range roles {
 		rr, err := roleResource(ctx, role, parentId)
@@ -84,7 +202,7 @@ func (o *roleResourceType) List(ctx context.Context, parentId *v2.ResourceId, to
 		rv = append(rv, rr)
 	}
 
-	return rv, "", nil, nil
+	return rv, "", annos, nil
 }
 
 func (o *roleResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -107,9 +225,9 @@ func (o *roleResourceType) Grants(ctx context.Context, resource *v2.Resource, to
 	if err != nil {
 		return nil, "", nil, err
 	}
-	admins, offset, err := o.client.GetAdmins(ctx, bag.PageToken())
+	admins, offset, annos, err := o.client.GetAdmins(ctx, bag.PageToken())
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list role members")
 	}
 	if offset != "" {
 		pageToken, err = bag.NextToken(offset)
@@ -120,7 +238,7 @@ func (o *roleResourceType) Grants(ctx context.Context, resource *v2.Resource, to
 
 	var rv []*v2.Grant
 	for _, admin := range admins {
-		if resource.DisplayName == admin.Role {
+		if roleId(admin.Role) == resource.Id.Resource {
 			adminCopy := admin
 			ar, err := adminResource(ctx, &adminCopy, resource.Id)
 			if err != nil {
@@ -132,12 +250,84 @@ func (o *roleResourceType) Grants(ctx context.Context, resource *v2.Resource, to
 		}
 	}
 
-	return rv, pageToken, nil, nil
+	return rv, pageToken, annos, nil
+}
+
+// Grant assigns the role to an admin, replacing the admin's current role.
+func (o *roleResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeAdmin.Id {
+		l.Warn(
+			"baton-duo: only admins can be granted a role",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-duo: only admins can be granted a role")
+	}
+
+	role, annos, err := o.roleName(ctx, entitlement.Resource)
+	if err != nil {
+		return annos, err
+	}
+
+	_, updateAnnos, err := o.client.UpdateAdmin(ctx, principal.Id.Resource, duo.AdminParams{Role: role})
+	annos = append(annos, updateAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error granting role")
+	}
+
+	return annos, nil
+}
+
+// Revoke demotes the admin to the fallback role, since a Duo admin always has a role.
+func (o *roleResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	entitlement := grant.Entitlement
+	principal := grant.Principal
+
+	if principal.Id.ResourceType != resourceTypeAdmin.Id {
+		l.Warn(
+			"baton-duo: only admins can have a role revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-duo: only admins can have a role revoked")
+	}
+
+	if entitlement.Resource.Id.Resource == roleId(o.fallbackRole) {
+		return nil, status.Errorf(codes.FailedPrecondition, "baton-duo: can't revoke the fallback role %s, grant another role instead", o.fallbackRole)
+	}
+
+	admin, annos, err := o.client.GetAdmin(ctx, principal.Id.Resource)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error fetching admin")
+	}
+
+	if roleId(admin.Role) != entitlement.Resource.Id.Resource {
+		l.Info(
+			"baton-duo: admin no longer has the role, nothing to revoke",
+			zap.String("admin_id", admin.AdminID),
+			zap.String("role", entitlement.Resource.Id.Resource),
+			zap.String("current_role", admin.Role),
+		)
+		return annos, nil
+	}
+
+	_, updateAnnos, err := o.client.UpdateAdmin(ctx, principal.Id.Resource, duo.AdminParams{Role: o.fallbackRole})
+	annos = append(annos, updateAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error revoking role")
+	}
+
+	return annos, nil
 }
 
-func roleBuilder(client *duo.Client) *roleResourceType {
+func roleBuilder(client *duo.Client, fallbackRole string) *roleResourceType {
 	return &roleResourceType{
 		resourceType: resourceTypeRole,
 		client:       client,
+		fallbackRole: fallbackRole,
 	}
 }
@@ -0,0 +1,164 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const assignedEntitlement = "assigned"
+
+type tokenResourceType struct {
+	resourceType *v2.ResourceType
+	client       *duo.Client
+	holders      *grantCache
+}
+
+func (o *tokenResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// Create a new connector resource for a Duo hardware token.
+func tokenResource(ctx context.Context, token *duo.Token, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	var totpStep string
+	if token.TOTPStep != nil {
+		totpStep = strconv.FormatInt(*token.TOTPStep, 10)
+	}
+
+	description := describe(
+		"type", token.Type,
+		"serial", token.Serial,
+		"totp_step", totpStep,
+	)
+
+	ret, err := rs.NewResource(
+		fmt.Sprintf("%s %s", token.Type, token.Serial),
+		resourceTypeToken,
+		token.TokenID,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (o *tokenResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	var pageToken string
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeToken.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	o.holders.startList(bag)
+
+	tokens, offset, annos, err := o.client.GetTokens(ctx, bag.PageToken())
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list tokens")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Resource
+	for _, t := range tokens {
+		tokenCopy := t
+		tr, err := tokenResource(ctx, &tokenCopy, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		holders, err := tokenHolders(&tokenCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		o.holders.set(t.TokenID, holders)
+		rv = append(rv, tr)
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+func (o *tokenResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeUser, resourceTypeAdmin),
+		ent.WithDescription(fmt.Sprintf("Assigned %s hardware token in Duo", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Token %s", resource.DisplayName, assignedEntitlement)),
+	}
+
+	en := ent.NewAssignmentEntitlement(resource, assignedEntitlement, assignmentOptions...)
+	rv = append(rv, en)
+
+	return rv, "", nil, nil
+}
+
+// Grants assigns a hardware token to the users and admins holding it. Duo lets
+// one token be shared, so a token can have several holders.
+func (o *tokenResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	rv, annos, err := o.holders.grants(ctx, resource, assignedEntitlement, o.fetchHolders)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return rv, "", annos, nil
+}
+
+// fetchHolders fetches the holders of a token that wasn't listed.
+func (o *tokenResourceType) fetchHolders(ctx context.Context, tokenId string) ([]*v2.ResourceId, annotations.Annotations, error) {
+	token, annos, err := o.client.GetToken(ctx, tokenId)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to fetch token")
+	}
+
+	holders, err := tokenHolders(&token)
+	return holders, annos, err
+}
+
+// tokenHolders returns the users and admins a token is assigned to.
+func tokenHolders(token *duo.Token) ([]*v2.ResourceId, error) {
+	var holders []*v2.ResourceId
+	for _, user := range token.Users {
+		principal, err := rs.NewResourceID(resourceTypeUser, user.UserID)
+		if err != nil {
+			return nil, err
+		}
+		holders = append(holders, principal)
+	}
+
+	for _, admin := range token.Admins {
+		principal, err := rs.NewResourceID(resourceTypeAdmin, admin.AdminID)
+		if err != nil {
+			return nil, err
+		}
+		holders = append(holders, principal)
+	}
+
+	return holders, nil
+}
+
+func tokenBuilder(client *duo.Client) *tokenResourceType {
+	return &tokenResourceType{
+		resourceType: resourceTypeToken,
+		client:       client,
+		holders:      newGrantCache(),
+	}
+}
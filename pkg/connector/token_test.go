@@ -0,0 +1,48 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestTokenListAndGrants(t *testing.T) {
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/tokens": respond([]duo.Token{
+			{
+				TokenID: "DHT1",
+				Type:    "h6",
+				Serial:  "0001",
+				Users:   []duo.User{{UserID: "U1"}},
+				Admins:  []duo.Admin{{AdminID: "DE1"}},
+			},
+		}),
+		"/admin/v1/tokens/DHT2": respond(duo.Token{TokenID: "DHT2", Type: "yk", Serial: "0002", Users: []duo.User{{UserID: "U2"}}}),
+	})
+	o := tokenBuilder(d.client)
+
+	tokens, _, _, err := o.List(context.Background(), testAccount.Id, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].DisplayName != "h6 0001" {
+		t.Fatalf("List() = %v; want the h6 0001 token", tokens)
+	}
+
+	if got, want := grantPrincipals(t, o, tokens[0]), []string{"user:U1", "admin:DE1"}; !slices.Equal(got, want) {
+		t.Errorf("DHT1 holders = %v; want %v", got, want)
+	}
+	if got := d.count("/admin/v1/tokens/DHT1"); got != 0 {
+		t.Errorf("listed token was fetched %d times; want 0", got)
+	}
+
+	// A token that wasn't listed is fetched on its own.
+	unlisted := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeToken.Id, Resource: "DHT2"}}
+	if got, want := grantPrincipals(t, o, unlisted), []string{"user:U2"}; !slices.Equal(got, want) {
+		t.Errorf("DHT2 holders = %v; want %v", got, want)
+	}
+}
@@ -3,19 +3,42 @@ package connector
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/conductorone/baton-duo/pkg/duo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	accountTypeUser  = "user"
+	accountTypeAdmin = "admin"
 )
 
 type userResourceType struct {
-	resourceType *v2.ResourceType
-	client       *duo.Client
+	resourceType   *v2.ResourceType
+	accounts       *accountClients
+	integrationKey string
+	users          *resourceCache
+	// admins gets the admins created through account provisioning.
+	admins *resourceCache
+
+	// disabledUsers gets the disabled users, by account, for the account's grants,
+	// once all of the account's users were listed. Until then they are gathered
+	// in listedDisabledUsers, which only has the accounts listed from the first page.
+	disabledUsers       *grantCache
+	listedDisabledUsers *grantCache
+
+	// bypassCodeParams configures the codes issued by Rotate, since the SDK's
+	// credential options have no fields for them.
+	bypassCodeParams duo.BypassCodeParams
 }
 
 func (o *userResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -42,14 +65,27 @@ func userResource(ctx context.Context, user *duo.User, parentResourceID *v2.Reso
 		"notes":      user.Notes,
 	}
 
+	aliases := userAliases(user)
+	if len(aliases) > 0 {
+		profileAliases := make([]interface{}, 0, len(aliases))
+		for _, alias := range aliases {
+			profileAliases = append(profileAliases, alias)
+		}
+		profile["aliases"] = profileAliases
+	}
+
+	for k, v := range enrollmentSummary(user) {
+		profile[k] = v
+	}
+
 	userStatus := v2.UserTrait_Status_STATUS_UNSPECIFIED
 
 	switch user.Status {
-	case "active":
+	case userStatusActive:
 		userStatus = v2.UserTrait_Status_STATUS_ENABLED
 	case "bypass":
 		userStatus = v2.UserTrait_Status_STATUS_ENABLED
-	case "disabled":
+	case userStatusDisabled:
 		userStatus = v2.UserTrait_Status_STATUS_DISABLED
 	case "locked out":
 		userStatus = v2.UserTrait_Status_STATUS_DISABLED
@@ -61,7 +97,7 @@ func userResource(ctx context.Context, user *duo.User, parentResourceID *v2.Reso
 		rs.WithUserProfile(profile),
 		rs.WithEmail(user.Email, true),
 		rs.WithStatus(userStatus),
-		rs.WithUserLogin(user.Username),
+		rs.WithUserLogin(user.Username, aliases...),
 	}
 
 	if user.Created > 0 {
@@ -96,9 +132,22 @@ func (o *userResourceType) List(ctx context.Context, parentId *v2.ResourceId, to
 		return nil, "", nil, err
 	}
 
-	users, offset, err := o.client.GetUsers(ctx, bag.PageToken())
+	client, annos, err := o.accounts.forParent(ctx, parentId)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("duo-connector: failed to list users: %w", err)
+		return nil, "", annos, err
+	}
+
+	// A resumed sync can start in the middle of the list, so the account's
+	// disabled users are only known when this process listed every page.
+	if bag.PageToken() == "" {
+		o.disabledUsers.delete(parentId.Resource)
+		o.listedDisabledUsers.set(parentId.Resource, nil)
+	}
+
+	users, offset, listAnnos, err := client.GetUsers(ctx, bag.PageToken())
+	annos = append(annos, listAnnos...)
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list users")
 	}
 
 	if offset != "" {
@@ -108,6 +157,8 @@ func (o *userResourceType) List(ctx context.Context, parentId *v2.ResourceId, to
 		}
 	}
 
+	_, listedFromStart := o.listedDisabledUsers.get(parentId.Resource)
+
 	var rv []*v2.Resource
 	for _, user := range users {
 		userCopy := user
@@ -115,10 +166,20 @@ func (o *userResourceType) List(ctx context.Context, parentId *v2.ResourceId, to
 		if err != nil {
 			return nil, "", nil, err
 		}
+		o.users.set(ur)
+		if listedFromStart && user.Status == userStatusDisabled {
+			o.listedDisabledUsers.add(parentId.Resource, ur.Id)
+		}
 		rv = append(rv, ur)
 	}
 
-	return rv, pageToken, nil, nil
+	if listedFromStart && pageToken == "" {
+		disabledUsers, _ := o.listedDisabledUsers.get(parentId.Resource)
+		o.disabledUsers.set(parentId.Resource, disabledUsers)
+		o.listedDisabledUsers.delete(parentId.Resource)
+	}
+
+	return rv, pageToken, annos, nil
 }
 
 func (o *userResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -129,9 +190,299 @@ func (o *userResourceType) Grants(_ context.Context, _ *v2.Resource, _ *paginati
 	return nil, "", nil, nil
 }
 
-func userBuilder(client *duo.Client) *userResourceType {
+// CreateAccount provisions a new Duo user from the account info. The SDK allows a
+// single account manager, so admins are created here too when the profile's
+// account_type is "admin". With child accounts, the profile's account_id picks
+// the child account to create it in.
+func (o *userResourceType) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	_ *v2.CredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	client, parentId, annos, err := o.accountClient(ctx, accountInfo)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	accountType, _ := rs.GetProfileStringValue(accountInfo.GetProfile(), "account_type")
+	switch accountType {
+	case "", accountTypeUser:
+	case accountTypeAdmin:
+		adminResult, plaintexts, adminAnnos, err := createAdminAccount(ctx, client, o.admins, parentId, accountInfo)
+		return adminResult, plaintexts, append(annos, adminAnnos...), err
+	default:
+		return nil, nil, nil, status.Errorf(codes.InvalidArgument, "baton-duo: unknown account type %q", accountType)
+	}
+
+	params := userParamsFromAccountInfo(accountInfo)
+	if params.Username == "" {
+		return nil, nil, nil, status.Error(codes.InvalidArgument, "baton-duo: a login or email is required to create a user")
+	}
+
+	user, createAnnos, err := client.CreateUser(ctx, params)
+	annos = append(annos, createAnnos...)
+	if err != nil {
+		return nil, nil, annos, wrapError(err, "baton-duo: error creating user")
+	}
+
+	ur, err := userResource(ctx, &user, parentId)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+	o.users.set(ur)
+
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource:              ur,
+		IsCreateAccountResult: true,
+	}, nil, annos, nil
+}
+
+// accountClient returns the client for the account a new account is created
+// in, and the ID of that account's resource, which is the new resource's parent.
+func (o *userResourceType) accountClient(ctx context.Context, accountInfo *v2.AccountInfo) (*duo.Client, *v2.ResourceId, annotations.Annotations, error) {
+	if !o.accounts.childAccounts {
+		parentId, err := rs.NewResourceID(resourceTypeAccount, o.integrationKey)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return o.accounts.client, parentId, nil, nil
+	}
+
+	accountId, _ := rs.GetProfileStringValue(accountInfo.GetProfile(), "account_id")
+	if accountId == "" {
+		return nil, nil, nil, status.Error(codes.InvalidArgument, "baton-duo: an account_id is required to create an account in a child account")
+	}
+
+	parentId, err := rs.NewResourceID(resourceTypeAccount, accountId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client, annos, err := o.accounts.forAccount(ctx, accountId)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	return client, parentId, annos, nil
+}
+
+func (o *userResourceType) Create(_ context.Context, _ *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	return nil, nil, status.Error(codes.Unimplemented, "baton-duo: users are created through account provisioning")
+}
+
+// Delete removes a Duo user. Use the account's "disabled" entitlement instead to
+// cut a user off while keeping the account for audit.
+func (o *userResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("baton-duo: only users can be deleted by this resource type")
+	}
+
+	client, annos, err := o.accounts.forResource(ctx, o.users, resourceId, lookupUser)
+	if err != nil {
+		return annos, err
+	}
+
+	deleteAnnos, err := client.DeleteUser(ctx, resourceId.Resource)
+	annos = append(annos, deleteAnnos...)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error deleting user")
+	}
+
+	return annos, nil
+}
+
+// enrollmentSummary describes how the user authenticates: whether they are
+// enrolled and which authenticators they have, so users who aren't enrolled or
+// only rely on SMS can be found.
+func enrollmentSummary(user *duo.User) map[string]interface{} {
+	types := make(map[string]bool)
+	for _, phone := range user.Phones {
+		for _, capability := range phone.Capabilities {
+			// "auto" only picks one of the phone's other capabilities.
+			if capability != "auto" {
+				types[capability] = true
+			}
+		}
+	}
+	if len(user.Tokens) > 0 {
+		types["hardware_token"] = true
+	}
+	if len(user.WebAuthnCreds) > 0 {
+		types["webauthn"] = true
+	}
+	if len(user.U2FTokens) > 0 {
+		types["u2f"] = true
+	}
+	if len(user.DesktopTokens) > 0 {
+		types["desktop"] = true
+	}
+
+	authenticatorTypes := make([]string, 0, len(types))
+	for t := range types {
+		authenticatorTypes = append(authenticatorTypes, t)
+	}
+	sort.Strings(authenticatorTypes)
+
+	profileTypes := make([]interface{}, 0, len(authenticatorTypes))
+	for _, t := range authenticatorTypes {
+		profileTypes = append(profileTypes, t)
+	}
+
+	groupNames := make([]interface{}, 0, len(user.Groups))
+	for _, group := range user.Groups {
+		groupNames = append(groupNames, group.Name)
+	}
+
+	rv := map[string]interface{}{
+		"is_enrolled":         user.IsEnrolled,
+		"phone_count":         len(user.Phones),
+		"token_count":         len(user.Tokens),
+		"webauthn_count":      len(user.WebAuthnCreds),
+		"u2f_token_count":     len(user.U2FTokens),
+		"desktop_token_count": len(user.DesktopTokens),
+		"authenticator_count": len(user.Phones) + len(user.Tokens) + len(user.WebAuthnCreds) + len(user.U2FTokens) + len(user.DesktopTokens),
+		"authenticator_types": profileTypes,
+		"sms_only":            len(authenticatorTypes) == 1 && authenticatorTypes[0] == "sms",
+		"groups":              groupNames,
+	}
+	if user.LastDirectorySync != nil {
+		rv["last_directory_sync"] = time.Unix(*user.LastDirectorySync, 0).UTC().Format(time.RFC3339)
+	}
+
+	return rv
+}
+
+// userAliases returns the user's aliases in slot order. Aliases can be set when
+// a user is created, but updating them afterwards is not supported: the SDK has
+// no hook for updating a resource.
+func userAliases(user *duo.User) []string {
+	slots := make([]string, 0, len(user.Aliases))
+	for slot, alias := range user.Aliases {
+		if alias != "" {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Strings(slots)
+
+	rv := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		rv = append(rv, user.Aliases[slot])
+	}
+
+	return rv
+}
+
+// Rotate issues new bypass codes for a user, replacing any existing ones. The
+// codes are returned as plaintext for the SDK to encrypt for the requester.
+func (o *userResourceType) Rotate(
+	ctx context.Context,
+	resourceId *v2.ResourceId,
+	_ *v2.CredentialOptions,
+) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	if resourceId.ResourceType != resourceTypeUser.Id {
+		return nil, nil, fmt.Errorf("baton-duo: only users can have bypass codes issued")
+	}
+
+	client, annos, err := o.accounts.forResource(ctx, o.users, resourceId, lookupUser)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	bypassCodes, createAnnos, err := client.CreateUserBypassCodes(ctx, resourceId.Resource, o.bypassCodeParams)
+	annos = append(annos, createAnnos...)
+	if err != nil {
+		return nil, annos, wrapError(err, "baton-duo: error creating bypass codes")
+	}
+
+	description := bypassCodeDescription(o.bypassCodeParams, time.Now())
+	var rv []*v2.PlaintextData
+	for _, code := range bypassCodes {
+		rv = append(rv, &v2.PlaintextData{
+			Name:        "bypass_code",
+			Description: description,
+			Bytes:       []byte(code),
+		})
+	}
+
+	return rv, annos, nil
+}
+
+// bypassCodeDescription tells the holder of a bypass code how long it can be used.
+func bypassCodeDescription(params duo.BypassCodeParams, issuedAt time.Time) string {
+	uses := "unlimited uses"
+	if params.ReuseCount > 0 {
+		uses = fmt.Sprintf("%d use(s)", params.ReuseCount)
+	}
+
+	if params.ValidSecs == 0 {
+		return fmt.Sprintf("Duo bypass code, valid for %s with no expiry", uses)
+	}
+
+	expiresAt := issuedAt.Add(time.Duration(params.ValidSecs) * time.Second)
+	return fmt.Sprintf("Duo bypass code, valid for %s until %s", uses, expiresAt.UTC().Format(time.RFC3339))
+}
+
+func userParamsFromAccountInfo(accountInfo *v2.AccountInfo) duo.UserParams {
+	profile := accountInfo.GetProfile()
+	profileValue := func(key string) string {
+		value, _ := rs.GetProfileStringValue(profile, key)
+		return value
+	}
+
+	var email string
+	for _, e := range accountInfo.GetEmails() {
+		if email == "" || e.GetIsPrimary() {
+			email = e.GetAddress()
+		}
+	}
+	if email == "" {
+		email = profileValue("email")
+	}
+
+	username := accountInfo.GetLogin()
+	if username == "" {
+		username = profileValue("username")
+	}
+	if username == "" {
+		username = email
+	}
+
+	firstName := profileValue("first_name")
+	lastName := profileValue("last_name")
+	realName := profileValue("realname")
+	if realName == "" {
+		realName = strings.TrimSpace(fmt.Sprintf("%s %s", firstName, lastName))
+	}
+
+	return duo.UserParams{
+		Username:  username,
+		Email:     email,
+		RealName:  realName,
+		FirstName: firstName,
+		LastName:  lastName,
+		Status:    profileValue("status"),
+		Notes:     profileValue("notes"),
+		Aliases:   accountInfo.GetLoginAliases(),
+	}
+}
+
+func userBuilder(
+	accounts *accountClients,
+	integrationKey string,
+	users *resourceCache,
+	admins *resourceCache,
+	disabledUsers *grantCache,
+	bypassCodeParams duo.BypassCodeParams,
+) *userResourceType {
 	return &userResourceType{
-		resourceType: resourceTypeUser,
-		client:       client,
+		resourceType:        resourceTypeUser,
+		accounts:            accounts,
+		integrationKey:      integrationKey,
+		users:               users,
+		admins:              admins,
+		disabledUsers:       disabledUsers,
+		listedDisabledUsers: newGrantCache(),
+		bypassCodeParams:    bypassCodeParams,
 	}
 }
@@ -0,0 +1,197 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type webAuthnCredentialResourceType struct {
+	resourceType *v2.ResourceType
+	client       *duo.Client
+	owners       *grantCache
+}
+
+func (o *webAuthnCredentialResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func webAuthnCredentialDisplayName(credential *duo.WebAuthnCredential) string {
+	switch {
+	case credential.Label != "":
+		return credential.Label
+	case credential.CredentialName != "":
+		return credential.CredentialName
+	default:
+		return credential.WebAuthnKey
+	}
+}
+
+// Create a new connector resource for a Duo WebAuthn credential.
+func webAuthnCredentialResource(ctx context.Context, credential *duo.WebAuthnCredential, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	var dateAdded, owner string
+	if credential.DateAdded > 0 {
+		dateAdded = time.Unix(credential.DateAdded, 0).UTC().Format(time.RFC3339)
+	}
+	switch {
+	case credential.User != nil:
+		owner = credential.User.Username
+	case credential.Admin != nil:
+		owner = credential.Admin.Email
+	}
+
+	description := describe(
+		"label", credential.Label,
+		"credential_name", credential.CredentialName,
+		"date_added", dateAdded,
+		"owner", owner,
+	)
+
+	ret, err := rs.NewResource(
+		webAuthnCredentialDisplayName(credential),
+		resourceTypeWebAuthnCredential,
+		credential.WebAuthnKey,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (o *webAuthnCredentialResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	var pageToken string
+	bag, err := parsePageToken(token.Token, &v2.ResourceId{ResourceType: resourceTypeWebAuthnCredential.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	o.owners.startList(bag)
+
+	credentials, offset, annos, err := o.client.GetWebAuthnCredentials(ctx, bag.PageToken())
+	if err != nil {
+		return nil, "", annos, wrapError(err, "duo-connector: failed to list webauthn credentials")
+	}
+
+	if offset != "" {
+		pageToken, err = bag.NextToken(offset)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var rv []*v2.Resource
+	for _, credential := range credentials {
+		credentialCopy := credential
+		cr, err := webAuthnCredentialResource(ctx, &credentialCopy, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		owners, err := webAuthnCredentialOwners(&credentialCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		o.owners.set(credential.WebAuthnKey, owners)
+		rv = append(rv, cr)
+	}
+
+	return rv, pageToken, annos, nil
+}
+
+func (o *webAuthnCredentialResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(resourceTypeUser, resourceTypeAdmin),
+		ent.WithDescription(fmt.Sprintf("Owner of %s WebAuthn credential in Duo", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s WebAuthn Credential %s", resource.DisplayName, ownerEntitlement)),
+	}
+
+	en := ent.NewAssignmentEntitlement(resource, ownerEntitlement, assignmentOptions...)
+	rv = append(rv, en)
+
+	return rv, "", nil, nil
+}
+
+// Grants makes the user or admin who registered a credential its owner. A
+// credential belongs to a single user or admin.
+func (o *webAuthnCredentialResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	rv, annos, err := o.owners.grants(ctx, resource, ownerEntitlement, o.fetchOwners)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return rv, "", annos, nil
+}
+
+// fetchOwners fetches the owner of a credential that wasn't listed.
+func (o *webAuthnCredentialResourceType) fetchOwners(ctx context.Context, webAuthnKey string) ([]*v2.ResourceId, annotations.Annotations, error) {
+	credential, annos, err := o.client.GetWebAuthnCredential(ctx, webAuthnKey)
+	if err != nil {
+		return nil, annos, wrapError(err, "duo-connector: failed to fetch webauthn credential")
+	}
+
+	owners, err := webAuthnCredentialOwners(&credential)
+	return owners, annos, err
+}
+
+// webAuthnCredentialOwners returns the user or admin who registered a credential.
+func webAuthnCredentialOwners(credential *duo.WebAuthnCredential) ([]*v2.ResourceId, error) {
+	var principal *v2.ResourceId
+	var err error
+	switch {
+	case credential.User != nil:
+		principal, err = rs.NewResourceID(resourceTypeUser, credential.User.UserID)
+	case credential.Admin != nil:
+		principal, err = rs.NewResourceID(resourceTypeAdmin, credential.Admin.AdminID)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []*v2.ResourceId{principal}, nil
+}
+
+func (o *webAuthnCredentialResourceType) Create(_ context.Context, _ *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	return nil, nil, status.Error(codes.Unimplemented, "baton-duo: webauthn credentials can only be registered by their owner")
+}
+
+// Delete revokes a WebAuthn credential, e.g. a lost or compromised security key.
+func (o *webAuthnCredentialResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != resourceTypeWebAuthnCredential.Id {
+		return nil, fmt.Errorf("baton-duo: only webauthn credentials can be deleted by this resource type")
+	}
+
+	annos, err := o.client.DeleteWebAuthnCredential(ctx, resourceId.Resource)
+	if err != nil {
+		return annos, wrapError(err, "baton-duo: error deleting webauthn credential")
+	}
+
+	return annos, nil
+}
+
+func webAuthnCredentialBuilder(client *duo.Client) *webAuthnCredentialResourceType {
+	return &webAuthnCredentialResourceType{
+		resourceType: resourceTypeWebAuthnCredential,
+		client:       client,
+		owners:       newGrantCache(),
+	}
+}
@@ -0,0 +1,64 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-duo/pkg/duo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestWebAuthnCredentialListAndGrants(t *testing.T) {
+	d := newTestDuo(t, map[string]testRoute{
+		"/admin/v1/webauthncredentials": func(r *http.Request) (interface{}, string) {
+			if r.URL.Query().Get("offset") == "0" {
+				return []duo.WebAuthnCredential{
+					{WebAuthnKey: "WA1", Label: "YubiKey", User: &duo.User{UserID: "U1", Username: "alice"}},
+				}, "1"
+			}
+			return []duo.WebAuthnCredential{
+				{WebAuthnKey: "WA2", CredentialName: "Touch ID", Admin: &duo.Admin{AdminID: "DE1", Email: "admin@example.com"}},
+			}, ""
+		},
+		"/admin/v1/webauthncredentials/WA3": respond(duo.WebAuthnCredential{WebAuthnKey: "WA3"}),
+	})
+	o := webAuthnCredentialBuilder(d.client)
+
+	var credentials []*v2.Resource
+	token := &pagination.Token{}
+	for {
+		page, next, _, err := o.List(context.Background(), testAccount.Id, token)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		credentials = append(credentials, page...)
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Token: next}
+	}
+	if len(credentials) != 2 {
+		t.Fatalf("List() returned %d credentials; want 2", len(credentials))
+	}
+
+	// Both pages stay cached, not only the last one.
+	if got, want := grantPrincipals(t, o, credentials[0]), []string{"user:U1"}; !slices.Equal(got, want) {
+		t.Errorf("WA1 owners = %v; want %v", got, want)
+	}
+	if got, want := grantPrincipals(t, o, credentials[1]), []string{"admin:DE1"}; !slices.Equal(got, want) {
+		t.Errorf("WA2 owners = %v; want %v", got, want)
+	}
+	if got := d.count("/admin/v1/webauthncredentials/WA1") + d.count("/admin/v1/webauthncredentials/WA2"); got != 0 {
+		t.Errorf("listed credentials were fetched %d times; want 0", got)
+	}
+
+	// A credential that wasn't listed is fetched on its own, and one without a
+	// user or admin has no owner grant.
+	unlisted := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeWebAuthnCredential.Id, Resource: "WA3"}}
+	if got := grantPrincipals(t, o, unlisted); len(got) != 0 {
+		t.Errorf("WA3 owners = %v; want none", got)
+	}
+}
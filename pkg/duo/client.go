@@ -8,16 +8,34 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
 const (
 	paginationLimit   = "100"
 	requestFailedStat = "FAIL"
+	maxUserAliases    = 8
+
+	// MaxLogLimit is the largest page the log endpoints return.
+	MaxLogLimit = 1000
+	// Kinds of members an administrative unit has, as named in its membership endpoints.
+	AdministrativeUnitAdmin       = "admin"
+	AdministrativeUnitGroup       = "group"
+	AdministrativeUnitIntegration = "integration"
+
+	// MaxTrustMonitorLimit is the largest page the Trust Monitor endpoint returns.
+	MaxTrustMonitorLimit = 200
 )
 
 type Client struct {
@@ -26,6 +44,9 @@ type Client struct {
 	secretKey      string
 	baseUrl        string
 	host           string
+	retry          retryPolicy
+	// accountId is set on clients acting on a child account through the Accounts API.
+	accountId string
 }
 
 func NewClient(integrationKey string, secretKey string, apiHostname string, httpClient *http.Client) *Client {
@@ -36,9 +57,21 @@ func NewClient(integrationKey string, secretKey string, apiHostname string, http
 		baseUrl:        baseUrl,
 		host:           apiHostname,
 		httpClient:     httpClient,
+		retry:          defaultRetryPolicy,
 	}
 }
 
+// ForAccount returns a client acting on a child account, using the credentials
+// of the Accounts API integration this client is configured with.
+func (c *Client) ForAccount(accountId string, apiHostname string) *Client {
+	child := *c
+	child.accountId = accountId
+	child.baseUrl = fmt.Sprintf("https://%s", apiHostname)
+	child.host = apiHostname
+
+	return &child
+}
+
 type ListResultMetadata struct {
 	NextOffset   json.Number `json:"next_offset"`
 	PrevOffset   json.Number `json:"prev_offset"`
@@ -65,6 +98,12 @@ type GroupsResponse struct {
 	Response []Group            `json:"response,omitempty"`
 }
 
+type GroupResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response Group  `json:"response"`
+}
+
 type GroupUsersResponse struct {
 	ErrorResponse
 	Metadata ListResultMetadata `json:"metadata"`
@@ -72,6 +111,12 @@ type GroupUsersResponse struct {
 	Response []User             `json:"response"`
 }
 
+type AdminRolesResponse struct {
+	ErrorResponse
+	Stat     string      `json:"stat"`
+	Response []AdminRole `json:"response,omitempty"`
+}
+
 type AdminsResponse struct {
 	ErrorResponse
 	Metadata ListResultMetadata `json:"metadata"`
@@ -79,18 +124,193 @@ type AdminsResponse struct {
 	Response []Admin            `json:"response"`
 }
 
+type AdminResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response Admin  `json:"response"`
+}
+
+type AdminActivationLinkResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response struct {
+		AdminID   string `json:"admin_id"`
+		Email     string `json:"email"`
+		Link      string `json:"link"`
+		ValidDays int64  `json:"valid_days"`
+	} `json:"response"`
+}
+
 type UserResponse struct {
 	ErrorResponse
 	Stat     string `json:"stat"`
 	Response User   `json:"response"`
 }
 
+type ChildAccountsResponse struct {
+	ErrorResponse
+	Stat     string         `json:"stat"`
+	Response []ChildAccount `json:"response"`
+}
+
 type AccountResponse struct {
 	ErrorResponse
 	Stat     string  `json:"stat"`
 	Response Account `json:"response"`
 }
 
+type PhonesResponse struct {
+	ErrorResponse
+	Metadata ListResultMetadata `json:"metadata"`
+	Stat     string             `json:"stat"`
+	Response []Phone            `json:"response"`
+}
+
+type PhoneResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response Phone  `json:"response"`
+}
+
+type TokensResponse struct {
+	ErrorResponse
+	Metadata ListResultMetadata `json:"metadata"`
+	Stat     string             `json:"stat"`
+	Response []Token            `json:"response"`
+}
+
+type TokenResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response Token  `json:"response"`
+}
+
+type WebAuthnCredentialsResponse struct {
+	ErrorResponse
+	Metadata ListResultMetadata   `json:"metadata"`
+	Stat     string               `json:"stat"`
+	Response []WebAuthnCredential `json:"response"`
+}
+
+type WebAuthnCredentialResponse struct {
+	ErrorResponse
+	Stat     string             `json:"stat"`
+	Response WebAuthnCredential `json:"response"`
+}
+
+type BypassCodesResponse struct {
+	ErrorResponse
+	Metadata ListResultMetadata `json:"metadata"`
+	Stat     string             `json:"stat"`
+	Response []BypassCode       `json:"response"`
+}
+
+type BypassCodeResponse struct {
+	ErrorResponse
+	Stat     string     `json:"stat"`
+	Response BypassCode `json:"response"`
+}
+
+// LogListMetadata is the metadata of the v2 log endpoints.
+type LogListMetadata struct {
+	NextOffset LogOffset `json:"next_offset"`
+}
+
+// LogOffset is the next_offset of the v2 log endpoints: a millisecond timestamp
+// and a transaction ID, sent either as a two element list or as a single
+// comma separated string depending on the endpoint.
+type LogOffset string
+
+func (o *LogOffset) UnmarshalJSON(data []byte) error {
+	var parts []string
+	if err := json.Unmarshal(data, &parts); err == nil {
+		*o = LogOffset(strings.Join(parts, ","))
+		return nil
+	}
+
+	var offset *string
+	if err := json.Unmarshal(data, &offset); err != nil {
+		return err
+	}
+	if offset != nil {
+		*o = LogOffset(*offset)
+	}
+
+	return nil
+}
+
+type TelephonyLogsResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response struct {
+		Items    []TelephonyLog  `json:"items"`
+		Metadata LogListMetadata `json:"metadata"`
+	} `json:"response"`
+}
+
+type TrustMonitorEventsResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response struct {
+		Events   []TrustMonitorEvent `json:"events"`
+		Metadata LogListMetadata     `json:"metadata"`
+	} `json:"response"`
+}
+
+type AuthLogsResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response struct {
+		AuthLogs []AuthLog       `json:"authlogs"`
+		Metadata LogListMetadata `json:"metadata"`
+	} `json:"response"`
+}
+
+type AdminLogsResponse struct {
+	ErrorResponse
+	Stat     string     `json:"stat"`
+	Response []AdminLog `json:"response"`
+}
+
+type AdministrativeUnitsResponse struct {
+	ErrorResponse
+	Metadata ListResultMetadata   `json:"metadata"`
+	Stat     string               `json:"stat"`
+	Response []AdministrativeUnit `json:"response,omitempty"`
+}
+
+type AdministrativeUnitResponse struct {
+	ErrorResponse
+	Stat     string             `json:"stat"`
+	Response AdministrativeUnit `json:"response"`
+}
+
+type PolicyResponse struct {
+	ErrorResponse
+	Stat     string `json:"stat"`
+	Response Policy `json:"response"`
+}
+
+type PoliciesResponse struct {
+	ErrorResponse
+	Metadata ListResultMetadata `json:"metadata"`
+	Stat     string             `json:"stat"`
+	Response []Policy           `json:"response,omitempty"`
+}
+
+type IntegrationsResponse struct {
+	ErrorResponse
+	Metadata ListResultMetadata `json:"metadata"`
+	Stat     string             `json:"stat"`
+	Response []Integration      `json:"response,omitempty"`
+}
+
+type IntegrationDetailResponse struct {
+	ErrorResponse
+	Stat     string      `json:"stat"`
+	Response Integration `json:"response"`
+}
+
 type IntegrationResponse struct {
 	ErrorResponse
 	Stat     string `json:"stat"`
@@ -100,6 +320,145 @@ type IntegrationResponse struct {
 	} `json:"response"`
 }
 
+// LogQuery selects a window of log entries. MinTime and MaxTime are Unix
+// timestamps in milliseconds, NextOffset is the cursor returned with the
+// previous page.
+type LogQuery struct {
+	MinTime    int64
+	MaxTime    int64
+	NextOffset string
+	Limit      int
+}
+
+func (q LogQuery) values() url.Values {
+	params := url.Values{}
+	params.Set("mintime", strconv.FormatInt(q.MinTime, 10))
+	params.Set("maxtime", strconv.FormatInt(q.MaxTime, 10))
+
+	limit := q.Limit
+	if limit <= 0 || limit > MaxLogLimit {
+		limit = MaxLogLimit
+	}
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("sort", "ts:asc")
+
+	if q.NextOffset != "" {
+		params.Set("next_offset", q.NextOffset)
+	}
+
+	return params
+}
+
+// UserParams holds the user attributes sent to Duo when creating or updating a
+// user. Empty values are not sent.
+type UserParams struct {
+	Username  string
+	Email     string
+	RealName  string
+	FirstName string
+	LastName  string
+	Status    string
+	Notes     string
+	Aliases   []string
+}
+
+func (p UserParams) values() (url.Values, error) {
+	if len(p.Aliases) > maxUserAliases {
+		return nil, fmt.Errorf("duo users can have at most %d aliases, got %d", maxUserAliases, len(p.Aliases))
+	}
+
+	data := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			data.Set(key, value)
+		}
+	}
+	set("username", p.Username)
+	set("email", p.Email)
+	set("realname", p.RealName)
+	set("firstname", p.FirstName)
+	set("lastname", p.LastName)
+	set("status", p.Status)
+	set("notes", p.Notes)
+	for i, alias := range p.Aliases {
+		set(fmt.Sprintf("alias%d", i+1), alias)
+	}
+
+	return data, nil
+}
+
+// GroupParams holds the group attributes sent to Duo when creating or updating
+// a group. Empty values are not sent.
+type GroupParams struct {
+	Name string
+	Desc string
+	// Status is one of "active", "bypass" or "disabled".
+	Status string
+}
+
+func (p GroupParams) values() url.Values {
+	data := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			data.Set(key, value)
+		}
+	}
+	set("name", p.Name)
+	set("desc", p.Desc)
+	set("status", p.Status)
+
+	return data
+}
+
+// BypassCodeParams controls the bypass codes generated for a user.
+type BypassCodeParams struct {
+	Count      int
+	ReuseCount int
+	// ValidSecs is how long the codes stay valid, 0 means forever.
+	ValidSecs int
+}
+
+func (p BypassCodeParams) values() url.Values {
+	data := url.Values{}
+	data.Set("count", strconv.Itoa(p.Count))
+	data.Set("reuse_count", strconv.Itoa(p.ReuseCount))
+	data.Set("valid_secs", strconv.Itoa(p.ValidSecs))
+
+	return data
+}
+
+// AdminParams holds the admin attributes sent to Duo when creating or updating
+// an admin. Empty values are not sent.
+type AdminParams struct {
+	Email                  string
+	Name                   string
+	Phone                  string
+	Role                   string
+	RestrictedByAdminUnits *bool
+	SendEmail              bool
+}
+
+func (p AdminParams) values() url.Values {
+	data := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			data.Set(key, value)
+		}
+	}
+	set("email", p.Email)
+	set("name", p.Name)
+	set("phone", p.Phone)
+	set("role", p.Role)
+	if p.RestrictedByAdminUnits != nil {
+		set("restricted_by_admin_units", strconv.FormatBool(*p.RestrictedByAdminUnits))
+	}
+	if p.SendEmail {
+		set("send_email", "1")
+	}
+
+	return data
+}
+
 // returns query params with pagination options.
 func paginationQuery(offset string) url.Values {
 	q := url.Values{}
@@ -161,253 +520,1120 @@ func sign(ikey string,
 }
 
 // GetUsers returns all users.
-func (c *Client) GetUsers(ctx context.Context, offset string) ([]User, string, error) {
+func (c *Client) GetUsers(ctx context.Context, offset string) ([]User, string, annotations.Annotations, error) {
 	uri := "/admin/v1/users"
 	usersUrl := fmt.Sprint(c.baseUrl, uri)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, usersUrl, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
 	params := paginationQuery(offset)
 	req.URL.RawQuery = params.Encode()
 
 	var res UsersResponse
-	if err := c.doRequest(uri, req, &res, params); err != nil {
-		return nil, "", err
-	}
-
-	if res.Stat == requestFailedStat {
-		return nil, "", fmt.Errorf("error fetching users: %s", res.Message)
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching users: %w", err)
 	}
 
 	if (res.Metadata != ListResultMetadata{}) {
-		return res.Response, res.Metadata.NextOffset.String(), nil
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
 	}
 
-	return res.Response, "", nil
+	return res.Response, "", annos, nil
 }
 
 // GetGroups returns all groups.
-func (c *Client) GetGroups(ctx context.Context, offset string) ([]Group, string, error) {
+func (c *Client) GetGroups(ctx context.Context, offset string) ([]Group, string, annotations.Annotations, error) {
 	uri := "/admin/v1/groups"
 	usersUrl := fmt.Sprint(c.baseUrl, uri)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, usersUrl, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
 	params := paginationQuery(offset)
 	req.URL.RawQuery = params.Encode()
 
 	var res GroupsResponse
-	if err := c.doRequest(uri, req, &res, params); err != nil {
-		return nil, "", err
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching groups: %w", err)
 	}
 
-	if res.Stat == requestFailedStat {
-		return nil, "", fmt.Errorf("error fetching groups: %s", res.Message)
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
 	}
 
-	if (res.Metadata != ListResultMetadata{}) {
-		return res.Response, res.Metadata.NextOffset.String(), nil
+	return res.Response, "", annos, nil
+}
+
+// GetGroup returns a group by ID.
+func (c *Client) GetGroup(ctx context.Context, groupId string) (Group, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v2/groups/%s", groupId)
+	groupUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, groupUrl, nil)
+	if err != nil {
+		return Group{}, nil, err
+	}
+
+	var res GroupResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return Group{}, annos, fmt.Errorf("error fetching a group: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// CreateGroup creates a new group.
+func (c *Client) CreateGroup(ctx context.Context, group GroupParams) (Group, annotations.Annotations, error) {
+	uri := "/admin/v1/groups"
+	createGroupUrl := fmt.Sprint(c.baseUrl, uri)
+	data := group.values()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, createGroupUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return Group{}, nil, err
+	}
+
+	var res GroupResponse
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return Group{}, annos, fmt.Errorf("error creating group: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// UpdateGroup updates the attributes of an existing group. Empty parameters are
+// left unchanged.
+func (c *Client) UpdateGroup(ctx context.Context, groupId string, group GroupParams) (Group, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/groups/%s", groupId)
+	updateGroupUrl := fmt.Sprint(c.baseUrl, uri)
+	data := group.values()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, updateGroupUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return Group{}, nil, err
+	}
+
+	var res GroupResponse
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return Group{}, annos, fmt.Errorf("error updating group: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// DeleteGroup deletes a group.
+func (c *Client) DeleteGroup(ctx context.Context, groupId string) (annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/groups/%s", groupId)
+	deleteGroupUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteGroupUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error deleting group: %w", err)
 	}
 
-	return res.Response, "", nil
+	return annos, nil
 }
 
 // GetGroupUsers returns all users in a group.
-func (c *Client) GetGroupUsers(ctx context.Context, groupId string, offset string) ([]User, string, error) {
+func (c *Client) GetGroupUsers(ctx context.Context, groupId string, offset string) ([]User, string, annotations.Annotations, error) {
 	uri := fmt.Sprintf("/admin/v2/groups/%s/users", groupId)
 	usersUrl := fmt.Sprint(c.baseUrl, uri)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, usersUrl, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
 	params := paginationQuery(offset)
 	req.URL.RawQuery = params.Encode()
 
 	var res GroupUsersResponse
-	if err := c.doRequest(uri, req, &res, params); err != nil {
-		return nil, "", err
-	}
-
-	if res.Stat == requestFailedStat {
-		return nil, "", fmt.Errorf("error fetching group users: %s", res.Message)
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching group users: %w", err)
 	}
 
 	if (res.Metadata != ListResultMetadata{}) {
-		return res.Response, res.Metadata.NextOffset.String(), nil
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
 	}
 
-	return res.Response, "", nil
+	return res.Response, "", annos, nil
 }
 
 // GetAdmins returns all admins.
-func (c *Client) GetAdmins(ctx context.Context, offset string) ([]Admin, string, error) {
+func (c *Client) GetAdmins(ctx context.Context, offset string) ([]Admin, string, annotations.Annotations, error) {
 	uri := "/admin/v1/admins"
 	adminsUrl := fmt.Sprint(c.baseUrl, uri)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, adminsUrl, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
 	params := paginationQuery(offset)
 	req.URL.RawQuery = params.Encode()
 
 	var res AdminsResponse
-	if err := c.doRequest(uri, req, &res, params); err != nil {
-		return nil, "", err
-	}
-
-	if res.Stat == requestFailedStat {
-		return nil, "", fmt.Errorf("error fetching admins: %s", res.Message)
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching admins: %w", err)
 	}
 
 	if (res.Metadata != ListResultMetadata{}) {
-		return res.Response, res.Metadata.NextOffset.String(), nil
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
 	}
 
-	return res.Response, "", nil
+	return res.Response, "", annos, nil
 }
 
-// GetUser returns a user by ID.
-func (c *Client) GetUser(ctx context.Context, userId string) (User, error) {
-	uri := fmt.Sprintf("/admin/v1/users/%s", userId)
-	adminsUrl := fmt.Sprint(c.baseUrl, uri)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, adminsUrl, nil)
+// GetCustomAdminRoles returns the custom admin roles defined on the account,
+// with the permissions each one grants.
+func (c *Client) GetCustomAdminRoles(ctx context.Context) ([]AdminRole, annotations.Annotations, error) {
+	uri := "/admin/v1/admins/custom_roles"
+	rolesUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rolesUrl, nil)
 	if err != nil {
-		return User{}, err
-	}
-
-	var res UserResponse
-	if err := c.doRequest(uri, req, &res, nil); err != nil {
-		return User{}, err
+		return nil, nil, err
 	}
 
-	if res.Stat == requestFailedStat {
-		return User{}, fmt.Errorf("error fetching a user: %s", res.Message)
+	var res AdminRolesResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return nil, annos, fmt.Errorf("error fetching custom admin roles: %w", err)
 	}
 
-	return res.Response, nil
+	return res.Response, annos, nil
 }
 
-// GetIntegration returns an integration by integration key.
-func (c *Client) GetIntegration(ctx context.Context) (IntegrationResponse, error) {
-	uri := fmt.Sprintf("/admin/v1/integrations/%s", c.integrationKey)
-	adminsUrl := fmt.Sprint(c.baseUrl, uri)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, adminsUrl, nil)
+// GetAdmin returns an admin by ID.
+func (c *Client) GetAdmin(ctx context.Context, adminId string) (Admin, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/admins/%s", adminId)
+	adminUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, adminUrl, nil)
 	if err != nil {
-		return IntegrationResponse{}, err
-	}
-
-	var res IntegrationResponse
-	if err := c.doRequest(uri, req, &res, nil); err != nil {
-		return IntegrationResponse{}, err
+		return Admin{}, nil, err
 	}
 
-	if res.Stat == requestFailedStat {
-		return IntegrationResponse{}, fmt.Errorf("error fetching integration: %s", res.Message)
+	var res AdminResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return Admin{}, annos, fmt.Errorf("error fetching an admin: %w", err)
 	}
 
-	return res, nil
+	return res.Response, annos, nil
 }
 
-// GetAccount returns account info.
-func (c *Client) GetAccount(ctx context.Context) (Account, error) {
-	uri := "/admin/v1/settings"
-	accountUrl := fmt.Sprint(c.baseUrl, uri)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, accountUrl, nil)
-	if err != nil {
-		return Account{}, err
-	}
+// UpdateAdmin updates the attributes of an existing admin, e.g. its role.
+func (c *Client) UpdateAdmin(ctx context.Context, adminId string, admin AdminParams) (Admin, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/admins/%s", adminId)
+	updateAdminUrl := fmt.Sprint(c.baseUrl, uri)
+	data := admin.values()
 
-	var res AccountResponse
-	if err := c.doRequest(uri, req, &res, nil); err != nil {
-		return Account{}, err
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, updateAdminUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return Admin{}, nil, err
 	}
 
-	if res.Stat == requestFailedStat {
-		return Account{}, fmt.Errorf("error fetching account: %s", res.Message)
+	var res AdminResponse
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return Admin{}, annos, fmt.Errorf("error updating admin: %w", err)
 	}
 
-	return res.Response, nil
+	return res.Response, annos, nil
 }
 
-// AddUserToGroup adds a user to a group.
-func (c *Client) AddUserToGroup(ctx context.Context, groupId, userId string) error {
-	uri := fmt.Sprint("/admin/v1/users/", userId, "/groups")
-	addUserUrl := fmt.Sprint(c.baseUrl, uri)
-	data := url.Values{}
-	data.Set("group_id", groupId)
+// CreateAdmin creates a new admin. Unless SendEmail is set, the admin stays
+// pending until activated through an activation link.
+func (c *Client) CreateAdmin(ctx context.Context, admin AdminParams) (Admin, annotations.Annotations, error) {
+	uri := "/admin/v1/admins"
+	createAdminUrl := fmt.Sprint(c.baseUrl, uri)
+	data := admin.values()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addUserUrl, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, createAdminUrl, strings.NewReader(data.Encode()))
 	if err != nil {
-		return err
+		return Admin{}, nil, err
 	}
 
-	var res struct {
-		Stat string `json:"stat"`
-		ErrorResponse
+	var res AdminResponse
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return Admin{}, annos, fmt.Errorf("error creating admin: %w", err)
 	}
 
-	if err := c.doRequest(uri, req, &res, data); err != nil {
-		return err
+	return res.Response, annos, nil
+}
+
+// CreateAdminActivationLink creates an activation link for an admin pending activation.
+func (c *Client) CreateAdminActivationLink(ctx context.Context, adminId string) (string, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/admins/%s/activation_link", adminId)
+	activationLinkUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, activationLinkUrl, nil)
+	if err != nil {
+		return "", nil, err
 	}
 
-	if res.Stat == requestFailedStat {
-		return fmt.Errorf("error adding user to group: %s", res.Message)
+	var res AdminActivationLinkResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return "", annos, fmt.Errorf("error creating admin activation link: %w", err)
 	}
 
-	return nil
+	return res.Response.Link, annos, nil
 }
 
-// RemoveUserFromGroup removes a user from a group.
-func (c *Client) RemoveUserFromGroup(ctx context.Context, groupId, userId string) error {
-	uri := fmt.Sprint("/admin/v1/users/", userId, "/groups/", groupId)
-	removeUserUrl := fmt.Sprint(c.baseUrl, uri)
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, removeUserUrl, nil)
+// DeleteAdmin deletes an admin.
+func (c *Client) DeleteAdmin(ctx context.Context, adminId string) (annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/admins/%s", adminId)
+	deleteAdminUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteAdminUrl, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var res struct {
 		Stat string `json:"stat"`
-		ErrorResponse
 	}
 
-	if err := c.doRequest(uri, req, &res, nil); err != nil {
-		return err
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error deleting admin: %w", err)
 	}
 
-	if res.Stat == requestFailedStat {
-		return fmt.Errorf("error removing user from group: %s", res.Message)
-	}
+	return annos, nil
+}
 
-	return nil
+// GetUser returns a user by ID.
+func (c *Client) GetUser(ctx context.Context, userId string) (User, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/users/%s", userId)
+	adminsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, adminsUrl, nil)
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	var res UserResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return User{}, annos, fmt.Errorf("error fetching a user: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// GetPhones returns all phones.
+func (c *Client) GetPhones(ctx context.Context, offset string) ([]Phone, string, annotations.Annotations, error) {
+	uri := "/admin/v1/phones"
+	phonesUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, phonesUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := paginationQuery(offset)
+	req.URL.RawQuery = params.Encode()
+
+	var res PhonesResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching phones: %w", err)
+	}
+
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
+	}
+
+	return res.Response, "", annos, nil
+}
+
+// GetPhone returns a phone by ID.
+func (c *Client) GetPhone(ctx context.Context, phoneId string) (Phone, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/phones/%s", phoneId)
+	phoneUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, phoneUrl, nil)
+	if err != nil {
+		return Phone{}, nil, err
+	}
+
+	var res PhoneResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return Phone{}, annos, fmt.Errorf("error fetching a phone: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// GetTokens returns all hardware tokens.
+func (c *Client) GetTokens(ctx context.Context, offset string) ([]Token, string, annotations.Annotations, error) {
+	uri := "/admin/v1/tokens"
+	tokensUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokensUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := paginationQuery(offset)
+	req.URL.RawQuery = params.Encode()
+
+	var res TokensResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching tokens: %w", err)
+	}
+
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
+	}
+
+	return res.Response, "", annos, nil
+}
+
+// GetToken returns a hardware token by ID.
+func (c *Client) GetToken(ctx context.Context, tokenId string) (Token, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/tokens/%s", tokenId)
+	tokenUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenUrl, nil)
+	if err != nil {
+		return Token{}, nil, err
+	}
+
+	var res TokenResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return Token{}, annos, fmt.Errorf("error fetching a token: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// GetWebAuthnCredentials returns all WebAuthn credentials.
+func (c *Client) GetWebAuthnCredentials(ctx context.Context, offset string) ([]WebAuthnCredential, string, annotations.Annotations, error) {
+	uri := "/admin/v1/webauthncredentials"
+	credentialsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, credentialsUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := paginationQuery(offset)
+	req.URL.RawQuery = params.Encode()
+
+	var res WebAuthnCredentialsResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching webauthn credentials: %w", err)
+	}
+
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
+	}
+
+	return res.Response, "", annos, nil
+}
+
+// GetWebAuthnCredential returns a WebAuthn credential by its key.
+func (c *Client) GetWebAuthnCredential(ctx context.Context, webAuthnKey string) (WebAuthnCredential, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/webauthncredentials/%s", webAuthnKey)
+	credentialUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, credentialUrl, nil)
+	if err != nil {
+		return WebAuthnCredential{}, nil, err
+	}
+
+	var res WebAuthnCredentialResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return WebAuthnCredential{}, annos, fmt.Errorf("error fetching a webauthn credential: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// DeleteWebAuthnCredential deletes a WebAuthn credential by its key.
+func (c *Client) DeleteWebAuthnCredential(ctx context.Context, webAuthnKey string) (annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/webauthncredentials/%s", webAuthnKey)
+	credentialUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, credentialUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error deleting webauthn credential: %w", err)
+	}
+
+	return annos, nil
+}
+
+// GetBypassCodes returns all outstanding bypass codes, without the codes themselves.
+func (c *Client) GetBypassCodes(ctx context.Context, offset string) ([]BypassCode, string, annotations.Annotations, error) {
+	uri := "/admin/v1/bypass_codes"
+	bypassCodesUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bypassCodesUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := paginationQuery(offset)
+	req.URL.RawQuery = params.Encode()
+
+	var res BypassCodesResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching bypass codes: %w", err)
+	}
+
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
+	}
+
+	return res.Response, "", annos, nil
+}
+
+// GetBypassCode returns a bypass code by ID.
+func (c *Client) GetBypassCode(ctx context.Context, bypassCodeId string) (BypassCode, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/bypass_codes/%s", bypassCodeId)
+	bypassCodeUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bypassCodeUrl, nil)
+	if err != nil {
+		return BypassCode{}, nil, err
+	}
+
+	var res BypassCodeResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return BypassCode{}, annos, fmt.Errorf("error fetching a bypass code: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// DeleteBypassCode deletes a bypass code by ID.
+func (c *Client) DeleteBypassCode(ctx context.Context, bypassCodeId string) (annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/bypass_codes/%s", bypassCodeId)
+	bypassCodeUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, bypassCodeUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error deleting bypass code: %w", err)
+	}
+
+	return annos, nil
+}
+
+// GetAuthenticationLogs returns a page of authentication log entries and the
+// offset of the next page, which is empty once the window is exhausted.
+func (c *Client) GetAuthenticationLogs(ctx context.Context, query LogQuery) ([]AuthLog, string, annotations.Annotations, error) {
+	uri := "/admin/v2/logs/authentication"
+	logsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logsUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := query.values()
+	req.URL.RawQuery = params.Encode()
+
+	var res AuthLogsResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching authentication logs: %w", err)
+	}
+
+	return res.Response.AuthLogs, string(res.Response.Metadata.NextOffset), annos, nil
+}
+
+// GetTelephonyLogs returns a page of telephony log entries and the offset of
+// the next page, which is empty once the window is exhausted.
+func (c *Client) GetTelephonyLogs(ctx context.Context, query LogQuery) ([]TelephonyLog, string, annotations.Annotations, error) {
+	uri := "/admin/v2/logs/telephony"
+	logsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logsUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := query.values()
+	req.URL.RawQuery = params.Encode()
+
+	var res TelephonyLogsResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching telephony logs: %w", err)
+	}
+
+	return res.Response.Items, string(res.Response.Metadata.NextOffset), annos, nil
+}
+
+// GetTrustMonitorEvents returns a page of the events surfaced by Trust Monitor
+// and the offset of the next page, which is empty once the window is exhausted.
+func (c *Client) GetTrustMonitorEvents(ctx context.Context, query LogQuery) ([]TrustMonitorEvent, string, annotations.Annotations, error) {
+	uri := "/admin/v1/trust_monitor/events"
+	eventsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, eventsUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Unlike the v2 logs, this endpoint takes its cursor as offset and has no sort order.
+	limit := query.Limit
+	if limit <= 0 || limit > MaxTrustMonitorLimit {
+		limit = MaxTrustMonitorLimit
+	}
+	params := url.Values{}
+	params.Set("mintime", strconv.FormatInt(query.MinTime, 10))
+	params.Set("maxtime", strconv.FormatInt(query.MaxTime, 10))
+	params.Set("limit", strconv.Itoa(limit))
+	if query.NextOffset != "" {
+		params.Set("offset", query.NextOffset)
+	}
+	req.URL.RawQuery = params.Encode()
+
+	var res TrustMonitorEventsResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching trust monitor events: %w", err)
+	}
+
+	return res.Response.Events, string(res.Response.Metadata.NextOffset), annos, nil
+}
+
+// GetAdministratorLogs returns up to MaxLogLimit administrator log entries with a
+// timestamp of minTime (Unix seconds) or later. Page by passing the latest
+// timestamp seen plus one.
+func (c *Client) GetAdministratorLogs(ctx context.Context, minTime int64) ([]AdminLog, annotations.Annotations, error) {
+	uri := "/admin/v1/logs/administrator"
+	logsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logsUrl, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := url.Values{}
+	params.Set("mintime", strconv.FormatInt(minTime, 10))
+	req.URL.RawQuery = params.Encode()
+
+	var res AdminLogsResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, annos, fmt.Errorf("error fetching administrator logs: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// GetPolicies returns all policies with their settings.
+func (c *Client) GetPolicies(ctx context.Context, offset string) ([]Policy, string, annotations.Annotations, error) {
+	uri := "/admin/v2/policies"
+	policiesUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, policiesUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := paginationQuery(offset)
+	req.URL.RawQuery = params.Encode()
+
+	var res PoliciesResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching policies: %w", err)
+	}
+
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
+	}
+
+	return res.Response, "", annos, nil
+}
+
+// GetGlobalPolicy returns the global policy, which applies wherever no other policy does.
+func (c *Client) GetGlobalPolicy(ctx context.Context) (Policy, annotations.Annotations, error) {
+	uri := "/admin/v2/policies/global"
+	policyUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, policyUrl, nil)
+	if err != nil {
+		return Policy{}, nil, err
+	}
+
+	var res PolicyResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return Policy{}, annos, fmt.Errorf("error fetching global policy: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// GetIntegrations returns all protected applications.
+func (c *Client) GetIntegrations(ctx context.Context, offset string) ([]Integration, string, annotations.Annotations, error) {
+	uri := "/admin/v1/integrations"
+	integrationsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, integrationsUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := paginationQuery(offset)
+	req.URL.RawQuery = params.Encode()
+
+	var res IntegrationsResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching integrations: %w", err)
+	}
+
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
+	}
+
+	return res.Response, "", annos, nil
+}
+
+// GetIntegrationByKey returns a protected application by its integration key.
+func (c *Client) GetIntegrationByKey(ctx context.Context, integrationKey string) (Integration, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/integrations/%s", integrationKey)
+	integrationUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, integrationUrl, nil)
+	if err != nil {
+		return Integration{}, nil, err
+	}
+
+	var res IntegrationDetailResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return Integration{}, annos, fmt.Errorf("error fetching integration: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// UpdateIntegrationGroupsAllowed replaces the groups allowed to authenticate to
+// a protected application. An empty list allows every user.
+func (c *Client) UpdateIntegrationGroupsAllowed(ctx context.Context, integrationKey string, groupIds []string) (Integration, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/integrations/%s", integrationKey)
+	integrationUrl := fmt.Sprint(c.baseUrl, uri)
+	data := url.Values{}
+	data.Set("groups_allowed", strings.Join(groupIds, ","))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, integrationUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return Integration{}, nil, err
+	}
+
+	var res IntegrationDetailResponse
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return Integration{}, annos, fmt.Errorf("error updating integration: %w", err)
+	}
+
+	return res.Response, annos, nil
 }
 
-func (c *Client) doRequest(uri string, req *http.Request, resType interface{}, params url.Values) error {
+// GetIntegration returns the integration the connector is configured with.
+func (c *Client) GetIntegration(ctx context.Context) (IntegrationResponse, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/integrations/%s", c.integrationKey)
+	adminsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, adminsUrl, nil)
+	if err != nil {
+		return IntegrationResponse{}, nil, err
+	}
+
+	var res IntegrationResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return IntegrationResponse{}, annos, fmt.Errorf("error fetching integration: %w", err)
+	}
+
+	return res, annos, nil
+}
+
+// GetChildAccounts returns the child accounts managed through the Accounts API.
+func (c *Client) GetChildAccounts(ctx context.Context) ([]ChildAccount, annotations.Annotations, error) {
+	uri := "/accounts/v1/account/list"
+	accountsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, accountsUrl, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res ChildAccountsResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return nil, annos, fmt.Errorf("error fetching child accounts: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// GetAccount returns account info.
+func (c *Client) GetAccount(ctx context.Context) (Account, annotations.Annotations, error) {
+	uri := "/admin/v1/settings"
+	accountUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, accountUrl, nil)
+	if err != nil {
+		return Account{}, nil, err
+	}
+
+	var res AccountResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return Account{}, annos, fmt.Errorf("error fetching account: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// CreateUser creates a new user.
+func (c *Client) CreateUser(ctx context.Context, user UserParams) (User, annotations.Annotations, error) {
+	uri := "/admin/v1/users"
+	createUserUrl := fmt.Sprint(c.baseUrl, uri)
+	data, err := user.values()
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, createUserUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	var res UserResponse
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return User{}, annos, fmt.Errorf("error creating user: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// UpdateUser updates the attributes of an existing user.
+func (c *Client) UpdateUser(ctx context.Context, userId string, user UserParams) (User, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/users/%s", userId)
+	updateUserUrl := fmt.Sprint(c.baseUrl, uri)
+	data, err := user.values()
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, updateUserUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	var res UserResponse
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return User{}, annos, fmt.Errorf("error updating user: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// CreateUserBypassCodes replaces the bypass codes of a user with newly
+// generated ones and returns them.
+func (c *Client) CreateUserBypassCodes(ctx context.Context, userId string, params BypassCodeParams) ([]string, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/users/%s/bypass_codes", userId)
+	bypassCodesUrl := fmt.Sprint(c.baseUrl, uri)
+	data := params.values()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, bypassCodesUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res struct {
+		Stat     string   `json:"stat"`
+		Response []string `json:"response"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return nil, annos, fmt.Errorf("error creating bypass codes: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// DeleteUser deletes a user. Duo keeps it in the trash as "pending deletion" for a few days.
+func (c *Client) DeleteUser(ctx context.Context, userId string) (annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/users/%s", userId)
+	deleteUserUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteUserUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error deleting user: %w", err)
+	}
+
+	return annos, nil
+}
+
+// GetAdministrativeUnits returns all administrative units.
+func (c *Client) GetAdministrativeUnits(ctx context.Context, offset string) ([]AdministrativeUnit, string, annotations.Annotations, error) {
+	uri := "/admin/v1/administrative_units"
+	unitsUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, unitsUrl, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	params := paginationQuery(offset)
+	req.URL.RawQuery = params.Encode()
+
+	var res AdministrativeUnitsResponse
+	annos, err := c.doRequest(uri, req, &res, params)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error fetching administrative units: %w", err)
+	}
+
+	if (res.Metadata != ListResultMetadata{}) {
+		return res.Response, res.Metadata.NextOffset.String(), annos, nil
+	}
+
+	return res.Response, "", annos, nil
+}
+
+// GetAdministrativeUnit returns an administrative unit by ID, with its admins, groups and integrations.
+func (c *Client) GetAdministrativeUnit(ctx context.Context, adminUnitId string) (AdministrativeUnit, annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/administrative_units/%s", adminUnitId)
+	unitUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, unitUrl, nil)
+	if err != nil {
+		return AdministrativeUnit{}, nil, err
+	}
+
+	var res AdministrativeUnitResponse
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return AdministrativeUnit{}, annos, fmt.Errorf("error fetching administrative unit: %w", err)
+	}
+
+	return res.Response, annos, nil
+}
+
+// AddAdministrativeUnitMember adds an admin, group or integration to an
+// administrative unit. memberType is one of the AdministrativeUnit* kinds.
+func (c *Client) AddAdministrativeUnitMember(ctx context.Context, adminUnitId, memberType, memberId string) (annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/administrative_units/%s/%s/%s", adminUnitId, memberType, memberId)
+	addMemberUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addMemberUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error adding %s to administrative unit: %w", memberType, err)
+	}
+
+	return annos, nil
+}
+
+// RemoveAdministrativeUnitMember removes an admin, group or integration from an
+// administrative unit. memberType is one of the AdministrativeUnit* kinds.
+func (c *Client) RemoveAdministrativeUnitMember(ctx context.Context, adminUnitId, memberType, memberId string) (annotations.Annotations, error) {
+	uri := fmt.Sprintf("/admin/v1/administrative_units/%s/%s/%s", adminUnitId, memberType, memberId)
+	removeMemberUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, removeMemberUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error removing %s from administrative unit: %w", memberType, err)
+	}
+
+	return annos, nil
+}
+
+// AddUserToGroup adds a user to a group.
+func (c *Client) AddUserToGroup(ctx context.Context, groupId, userId string) (annotations.Annotations, error) {
+	uri := fmt.Sprint("/admin/v1/users/", userId, "/groups")
+	addUserUrl := fmt.Sprint(c.baseUrl, uri)
+	data := url.Values{}
+	data.Set("group_id", groupId)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addUserUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, data)
+	if err != nil {
+		return annos, fmt.Errorf("error adding user to group: %w", err)
+	}
+
+	return annos, nil
+}
+
+// RemoveUserFromGroup removes a user from a group.
+func (c *Client) RemoveUserFromGroup(ctx context.Context, groupId, userId string) (annotations.Annotations, error) {
+	uri := fmt.Sprint("/admin/v1/users/", userId, "/groups/", groupId)
+	removeUserUrl := fmt.Sprint(c.baseUrl, uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, removeUserUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Stat string `json:"stat"`
+	}
+
+	annos, err := c.doRequest(uri, req, &res, nil)
+	if err != nil {
+		return annos, fmt.Errorf("error removing user from group: %w", err)
+	}
+
+	return annos, nil
+}
+
+func (c *Client) doRequest(uri string, req *http.Request, resType interface{}, params url.Values) (annotations.Annotations, error) {
+	ctx := req.Context()
+	l := ctxzap.Extract(ctx)
+
+	params = c.scopeToAccount(req, params)
+
+	var annos annotations.Annotations
+	for attempt := 1; ; attempt++ {
+		statusCode, header, body, err := c.send(uri, req, params)
+		if err != nil {
+			return annos, err
+		}
+
+		rateLimited := isRateLimited(statusCode, body)
+		if !isRetryable(req.Method, statusCode, body) || attempt >= c.retry.maxAttempts {
+			if rateLimited {
+				annos = rateLimitAnnotations(v2.RateLimitDescription_STATUS_OVERLIMIT, time.Now().Add(c.retry.delay(attempt, header)))
+			}
+
+			return annos, decodeResponse(statusCode, header, body, resType)
+		}
+
+		delay := c.retry.delay(attempt, header)
+		if rateLimited {
+			annos = rateLimitAnnotations(v2.RateLimitDescription_STATUS_OK, time.Now().Add(delay))
+		}
+
+		l.Debug(
+			"duo: retrying request",
+			zap.String("uri", uri),
+			zap.Int("status_code", statusCode),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+		)
+
+		if err := sleep(ctx, delay); err != nil {
+			return annos, err
+		}
+	}
+}
+
+// scopeToAccount adds the account_id parameter to requests made on a child
+// account, in the query or the form body depending on the method.
+func (c *Client) scopeToAccount(req *http.Request, params url.Values) url.Values {
+	if c.accountId == "" {
+		return params
+	}
+
+	scoped := url.Values{}
+	for k, v := range params {
+		scoped[k] = v
+	}
+	scoped.Set("account_id", c.accountId)
+
+	encoded := scoped.Encode()
+	if req.Method == http.MethodPost {
+		req.Body = io.NopCloser(strings.NewReader(encoded))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(encoded)), nil
+		}
+		req.ContentLength = int64(len(encoded))
+	} else {
+		req.URL.RawQuery = encoded
+	}
+
+	return scoped
+}
+
+// send signs and performs a single attempt of the request. Each attempt gets a
+// fresh Date header and signature, since Duo rejects stale signatures.
+func (c *Client) send(uri string, req *http.Request, params url.Values) (int, http.Header, []byte, error) {
 	now := time.Now().UTC().Format(time.RFC1123Z)
 	signature, err := sign(c.integrationKey, c.secretKey, req.Method, c.host, uri, now, params)
 	if err != nil {
-		return err
+		return 0, nil, nil, err
 	}
 
-	req.Header.Add("Authorization", signature)
-	req.Header.Add("Date", now)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", signature)
+	req.Header.Set("Date", now)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		req.Body = body
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return 0, nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(&resType); err != nil {
-		return err
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, err
 	}
 
-	return nil
+	return resp.StatusCode, resp.Header, body, nil
 }
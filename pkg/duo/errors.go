@@ -0,0 +1,133 @@
+package duo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodyLength caps how much of a non-JSON body is kept on an APIError.
+const maxErrorBodyLength = 256
+
+// APIError is returned when Duo responds with a failure. Code is Duo's numeric
+// error code, whose first three digits are the HTTP status (e.g. 40301).
+type APIError struct {
+	StatusCode    int
+	Code          int64
+	Message       string
+	MessageDetail string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("duo: request failed with status %d", e.StatusCode)
+	if e.Code != 0 {
+		msg = fmt.Sprintf("%s (code %d)", msg, e.Code)
+	}
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+	if e.MessageDetail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.MessageDetail)
+	}
+
+	return msg
+}
+
+// status returns the HTTP status the error represents, falling back to the
+// status encoded in Duo's error code.
+func (e *APIError) status() int {
+	if e.StatusCode != 0 && e.StatusCode != http.StatusOK {
+		return e.StatusCode
+	}
+
+	return int(e.Code / 100)
+}
+
+func hasStatus(err error, statusCodes ...int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	for _, statusCode := range statusCodes {
+		if apiErr.status() == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsNotFound reports whether the requested Duo object does not exist.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsRateLimited reports whether Duo rejected the request for exceeding its rate limit.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsUnauthorized reports whether Duo rejected the request signature or integration key.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsPermissionDenied reports whether the integration lacks the permission for the request,
+// e.g. "Grant read resource".
+func IsPermissionDenied(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsInvalidRequest reports whether Duo rejected the request parameters.
+func IsInvalidRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest, http.StatusMethodNotAllowed)
+}
+
+// IsServerError reports whether Duo failed to serve the request on its side.
+func IsServerError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.status() >= http.StatusInternalServerError
+}
+
+// decodeResponse turns a Duo response into resType, or into an APIError when
+// Duo reports a failure or the body is not JSON (e.g. an HTML page from a proxy).
+func decodeResponse(statusCode int, header http.Header, body []byte, resType interface{}) error {
+	var res struct {
+		Stat string `json:"stat"`
+		ErrorResponse
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return &APIError{
+			StatusCode:    statusCode,
+			Message:       fmt.Sprintf("unexpected non-JSON response with content type %q", header.Get("Content-Type")),
+			MessageDetail: truncateBody(body),
+		}
+	}
+
+	if res.Stat == requestFailedStat || statusCode >= http.StatusBadRequest {
+		return &APIError{
+			StatusCode:    statusCode,
+			Code:          res.Code,
+			Message:       res.Message,
+			MessageDetail: res.MessageDetail,
+		}
+	}
+
+	return json.Unmarshal(body, resType)
+}
+
+func truncateBody(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) > maxErrorBodyLength {
+		return s[:maxErrorBodyLength] + "..."
+	}
+
+	return s
+}
@@ -0,0 +1,133 @@
+package duo
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeResponse(t *testing.T) {
+	jsonHeader := http.Header{"Content-Type": []string{"application/json"}}
+	htmlHeader := http.Header{"Content-Type": []string{"text/html"}}
+
+	tests := []struct {
+		name       string
+		statusCode int
+		header     http.Header
+		body       string
+		wantErr    *APIError
+		wantName   string
+	}{
+		{
+			name:       "ok",
+			statusCode: http.StatusOK,
+			header:     jsonHeader,
+			body:       `{"stat": "OK", "response": {"name": "Engineering"}}`,
+			wantName:   "Engineering",
+		},
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			header:     jsonHeader,
+			body:       `{"stat": "FAIL", "code": 40401, "message": "Resource not found"}`,
+			wantErr:    &APIError{StatusCode: http.StatusNotFound, Code: 40401, Message: "Resource not found"},
+		},
+		{
+			name:       "failure with message detail",
+			statusCode: http.StatusBadRequest,
+			header:     jsonHeader,
+			body:       `{"stat": "FAIL", "code": 40003, "message": "Duplicate resource", "message_detail": "name"}`,
+			wantErr:    &APIError{StatusCode: http.StatusBadRequest, Code: 40003, Message: "Duplicate resource", MessageDetail: "name"},
+		},
+		{
+			name:       "failure stat on a 200",
+			statusCode: http.StatusOK,
+			header:     jsonHeader,
+			body:       `{"stat": "FAIL", "code": 40301, "message": "Access forbidden"}`,
+			wantErr:    &APIError{StatusCode: http.StatusOK, Code: 40301, Message: "Access forbidden"},
+		},
+		{
+			name:       "error status without a failure stat",
+			statusCode: http.StatusInternalServerError,
+			header:     jsonHeader,
+			body:       `{}`,
+			wantErr:    &APIError{StatusCode: http.StatusInternalServerError},
+		},
+		{
+			name:       "non-JSON body",
+			statusCode: http.StatusBadGateway,
+			header:     htmlHeader,
+			body:       "<html>Bad Gateway</html>",
+			wantErr: &APIError{
+				StatusCode:    http.StatusBadGateway,
+				Message:       `unexpected non-JSON response with content type "text/html"`,
+				MessageDetail: "<html>Bad Gateway</html>",
+			},
+		},
+		{
+			name:       "long non-JSON body is truncated",
+			statusCode: http.StatusServiceUnavailable,
+			header:     htmlHeader,
+			body:       strings.Repeat("x", maxErrorBodyLength+10),
+			wantErr: &APIError{
+				StatusCode:    http.StatusServiceUnavailable,
+				Message:       `unexpected non-JSON response with content type "text/html"`,
+				MessageDetail: strings.Repeat("x", maxErrorBodyLength) + "...",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res GroupResponse
+			err := decodeResponse(tt.statusCode, tt.header, []byte(tt.body), &res)
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("decodeResponse() error = %v; want nil", err)
+				}
+				if res.Response.Name != tt.wantName {
+					t.Errorf("decodeResponse() name = %q; want %q", res.Response.Name, tt.wantName)
+				}
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("decodeResponse() error = %v; want an *APIError", err)
+			}
+			if *apiErr != *tt.wantErr {
+				t.Errorf("decodeResponse() error = %+v; want %+v", *apiErr, *tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPIErrorPredicates(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		is   func(error) bool
+		want bool
+	}{
+		{name: "not found by status", err: &APIError{StatusCode: http.StatusNotFound}, is: IsNotFound, want: true},
+		{name: "not found by code on a 200", err: &APIError{StatusCode: http.StatusOK, Code: 40401}, is: IsNotFound, want: true},
+		{name: "rate limited", err: &APIError{StatusCode: http.StatusTooManyRequests, Code: 42901}, is: IsRateLimited, want: true},
+		{name: "unauthorized", err: &APIError{StatusCode: http.StatusUnauthorized, Code: 40103}, is: IsUnauthorized, want: true},
+		{name: "permission denied", err: &APIError{StatusCode: http.StatusForbidden, Code: 40301}, is: IsPermissionDenied, want: true},
+		{name: "method not allowed is invalid", err: &APIError{StatusCode: http.StatusMethodNotAllowed}, is: IsInvalidRequest, want: true},
+		{name: "server error", err: &APIError{StatusCode: http.StatusServiceUnavailable}, is: IsServerError, want: true},
+		{name: "wrapped", err: errors.Join(errors.New("error fetching users"), &APIError{StatusCode: http.StatusNotFound}), is: IsNotFound, want: true},
+		{name: "other status", err: &APIError{StatusCode: http.StatusBadRequest}, is: IsNotFound, want: false},
+		{name: "not an APIError", err: errors.New("connection reset"), is: IsServerError, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.is(tt.err); got != tt.want {
+				t.Errorf("predicate(%v) = %v; want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,28 @@ type User struct {
 	Created   int64  `json:"created"`
 	LastLogin int64  `json:"last_login"`
 	Notes     string `json:"notes"`
+	// Aliases are keyed by slot, alias1 to alias8.
+	Aliases           map[string]string    `json:"aliases"`
+	IsEnrolled        bool                 `json:"is_enrolled"`
+	LastDirectorySync *int64               `json:"last_directory_sync"`
+	Groups            []Group              `json:"groups"`
+	Phones            []Phone              `json:"phones"`
+	Tokens            []Token              `json:"tokens"`
+	WebAuthnCreds     []WebAuthnCredential `json:"webauthncredentials"`
+	U2FTokens         []U2FToken           `json:"u2ftokens"`
+	DesktopTokens     []DesktopToken       `json:"desktoptokens"`
+}
+
+type U2FToken struct {
+	RegistrationID string `json:"registration_id"`
+	DateAdded      int64  `json:"date_added"`
+}
+
+type DesktopToken struct {
+	DesktopTokenID string `json:"desktoptoken_id"`
+	Name           string `json:"name"`
+	Platform       string `json:"platform"`
+	Type           string `json:"type"`
 }
 
 type Group struct {
@@ -31,3 +53,154 @@ type Admin struct {
 type Account struct {
 	Name string `json:"name"`
 }
+
+type Phone struct {
+	PhoneID          string   `json:"phone_id"`
+	Name             string   `json:"name"`
+	Number           string   `json:"number"`
+	Extension        string   `json:"extension"`
+	Type             string   `json:"type"`
+	Platform         string   `json:"platform"`
+	Model            string   `json:"model"`
+	Activated        bool     `json:"activated"`
+	SMSPasscodesSent bool     `json:"sms_passcodes_sent"`
+	LastSeen         string   `json:"last_seen"`
+	Capabilities     []string `json:"capabilities"`
+	Users            []User   `json:"users"`
+}
+
+type Token struct {
+	TokenID  string  `json:"token_id"`
+	Type     string  `json:"type"`
+	Serial   string  `json:"serial"`
+	TOTPStep *int64  `json:"totp_step"`
+	Admins   []Admin `json:"admins"`
+	Users    []User  `json:"users"`
+}
+
+type WebAuthnCredential struct {
+	WebAuthnKey    string `json:"webauthnkey"`
+	CredentialName string `json:"credential_name"`
+	Label          string `json:"label"`
+	DateAdded      int64  `json:"date_added"`
+	User           *User  `json:"user,omitempty"`
+	Admin          *Admin `json:"admin,omitempty"`
+}
+
+type AuthLog struct {
+	TxID        string             `json:"txid"`
+	Timestamp   int64              `json:"timestamp"`
+	EventType   string             `json:"event_type"`
+	Result      string             `json:"result"`
+	Reason      string             `json:"reason"`
+	Factor      string             `json:"factor"`
+	Application AuthLogApplication `json:"application"`
+	User        AuthLogUser        `json:"user"`
+}
+
+type AuthLogApplication struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type AuthLogUser struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type AdminLog struct {
+	Action      string `json:"action"`
+	Description string `json:"description"`
+	Object      string `json:"object"`
+	Timestamp   int64  `json:"timestamp"`
+	Username    string `json:"username"`
+}
+
+type TelephonyLog struct {
+	TelephonyID string `json:"telephony_id"`
+	TxID        string `json:"txid"`
+	Context     string `json:"context"`
+	Credits     int64  `json:"credits"`
+	Phone       string `json:"phone"`
+	Type        string `json:"type"`
+	Timestamp   string `json:"ts"`
+}
+
+type TrustMonitorEvent struct {
+	SEKey             string               `json:"sekey"`
+	Type              string               `json:"type"`
+	State             string               `json:"state"`
+	SurfacedTimestamp int64                `json:"surfaced_timestamp"`
+	PriorityEvent     bool                 `json:"priority_event"`
+	PriorityReasons   []TrustMonitorReason `json:"priority_reasons"`
+	Explanations      []TrustMonitorReason `json:"explanations"`
+	SurfacedAuth      *AuthLog             `json:"surfaced_auth"`
+	EnabledBy         *AuthLogUser         `json:"enabled_by"`
+	EnabledFor        *AuthLogUser         `json:"enabled_for"`
+}
+
+type TrustMonitorReason struct {
+	Type    string `json:"type"`
+	Label   string `json:"label"`
+	Summary string `json:"summary"`
+}
+
+type Integration struct {
+	IntegrationKey string   `json:"integration_key"`
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`
+	GroupsAllowed  []string `json:"groups_allowed"`
+	PolicyKey      string   `json:"policy_key"`
+	EnrollPolicy   string   `json:"enroll_policy"`
+	Notes          string   `json:"notes"`
+	// GroupPolicies are the policies applied to some groups of the integration
+	// only, on top of its PolicyKey.
+	GroupPolicies []IntegrationGroupPolicy `json:"group_policies"`
+}
+
+type IntegrationGroupPolicy struct {
+	PolicyKey string   `json:"policy_key"`
+	GroupIDs  []string `json:"group_id_list"`
+}
+
+type AdminRole struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type AdministrativeUnit struct {
+	AdminUnitID            string   `json:"admin_unit_id"`
+	Name                   string   `json:"name"`
+	Description            string   `json:"description"`
+	RestrictByGroups       bool     `json:"restrict_by_groups"`
+	RestrictByIntegrations bool     `json:"restrict_by_integrations"`
+	Admins                 []string `json:"admins"`
+	Groups                 []string `json:"groups"`
+	Integrations           []string `json:"integrations"`
+}
+
+type BypassCode struct {
+	BypassCodeID string `json:"bypass_code_id"`
+	AdminEmail   string `json:"admin_email"`
+	Created      int64  `json:"created"`
+	// Expiration and ReuseCount are null when the code never expires or has unlimited uses.
+	Expiration *int64 `json:"expiration"`
+	ReuseCount *int64 `json:"reuse_count"`
+	User       *User  `json:"user"`
+}
+
+type ChildAccount struct {
+	AccountID   string `json:"account_id"`
+	Name        string `json:"name"`
+	APIHostname string `json:"api_hostname"`
+}
+
+type Policy struct {
+	PolicyKey      string `json:"policy_key"`
+	PolicyName     string `json:"policy_name"`
+	IsGlobalPolicy bool   `json:"is_global_policy"`
+	// Sections holds the policy settings, keyed by section such as
+	// "authentication_methods" or "new_user".
+	Sections map[string]map[string]interface{} `json:"sections"`
+}
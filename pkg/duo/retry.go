@@ -0,0 +1,133 @@
+package duo
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Duo returns this code in the body of a 429 response.
+const rateLimitedCode = 42901
+
+// retryPolicy controls how requests are retried when Duo rate limits us or
+// fails with a transient server error. The defaults follow Duo's guidance of
+// starting at one second and doubling up to 32 seconds, with random jitter.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	maxJitter   time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	maxAttempts: 7,
+	baseDelay:   time.Second,
+	maxDelay:    32 * time.Second,
+	maxJitter:   time.Second,
+}
+
+// delay returns how long to wait before the next attempt. A Retry-After header
+// takes precedence over the computed exponential backoff, but is capped at
+// maxDelay so a bogus value can't stall a sync.
+func (p retryPolicy) delay(attempt int, header http.Header) time.Duration {
+	if d, ok := retryAfter(header, time.Now()); ok {
+		return min(d, p.maxDelay)
+	}
+
+	d := p.baseDelay << (attempt - 1)
+	if d <= 0 || d > p.maxDelay {
+		d = p.maxDelay
+	}
+
+	if p.maxJitter > 0 {
+		d += time.Duration(rand.Int63n(int64(p.maxJitter))) //nolint:gosec // jitter does not need a secure source.
+	}
+
+	return d
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// isRateLimited reports whether a response is Duo telling us to slow down.
+func isRateLimited(statusCode int, body []byte) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	var res ErrorResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return false
+	}
+
+	return res.Code == rateLimitedCode
+}
+
+// isRetryable reports whether a request should be attempted again. Only GETs
+// are retried on server errors: a POST or DELETE that failed with a 5xx may
+// still have been applied, while a rate limited one never reached Duo.
+func isRetryable(method string, statusCode int, body []byte) bool {
+	if method != http.MethodGet {
+		return isRateLimited(statusCode, body)
+	}
+
+	switch statusCode {
+	case http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return isRateLimited(statusCode, body)
+}
+
+// rateLimitAnnotations describes a rate limit that was hit while serving a request.
+func rateLimitAnnotations(status v2.RateLimitDescription_Status, resetAt time.Time) annotations.Annotations {
+	annos := annotations.Annotations{}
+	annos.WithRateLimiting(&v2.RateLimitDescription{
+		Status:    status,
+		Remaining: 0,
+		ResetAt:   timestamppb.New(resetAt),
+	})
+
+	return annos
+}
+
+// sleep waits for the given duration unless the context is cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
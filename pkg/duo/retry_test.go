@@ -0,0 +1,130 @@
+package duo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "missing", value: "", want: 0, wantOk: false},
+		{name: "seconds", value: "7", want: 7 * time.Second, wantOk: true},
+		{name: "zero seconds", value: "0", want: 0, wantOk: true},
+		{name: "negative seconds", value: "-3", want: 0, wantOk: false},
+		{name: "http date", value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second, wantOk: true},
+		{name: "http date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, wantOk: true},
+		{name: "garbage", value: "soon", want: 0, wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+
+			got, ok := retryAfter(header, now)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	rateLimitedBody := []byte(`{"stat": "FAIL", "code": 42901, "message": "Too Many Requests"}`)
+	serverErrorBody := []byte(`{"stat": "FAIL", "code": 50000, "message": "Internal server error"}`)
+	badRequestBody := []byte(`{"stat": "FAIL", "code": 40002, "message": "Invalid request parameters"}`)
+
+	tests := []struct {
+		name       string
+		method     string
+		statusCode int
+		body       []byte
+		want       bool
+	}{
+		{name: "get ok", method: http.MethodGet, statusCode: http.StatusOK, body: []byte(`{"stat": "OK"}`), want: false},
+		{name: "get rate limited", method: http.MethodGet, statusCode: http.StatusTooManyRequests, body: rateLimitedBody, want: true},
+		{name: "get rate limited code only", method: http.MethodGet, statusCode: http.StatusOK, body: rateLimitedBody, want: true},
+		{name: "get server error", method: http.MethodGet, statusCode: http.StatusInternalServerError, body: serverErrorBody, want: true},
+		{name: "get bad gateway", method: http.MethodGet, statusCode: http.StatusBadGateway, body: []byte("<html></html>"), want: true},
+		{name: "get service unavailable", method: http.MethodGet, statusCode: http.StatusServiceUnavailable, body: nil, want: true},
+		{name: "get gateway timeout", method: http.MethodGet, statusCode: http.StatusGatewayTimeout, body: nil, want: true},
+		{name: "get bad request", method: http.MethodGet, statusCode: http.StatusBadRequest, body: badRequestBody, want: false},
+		{name: "post rate limited", method: http.MethodPost, statusCode: http.StatusTooManyRequests, body: rateLimitedBody, want: true},
+		{name: "post rate limited code only", method: http.MethodPost, statusCode: http.StatusOK, body: rateLimitedBody, want: true},
+		{name: "post server error", method: http.MethodPost, statusCode: http.StatusInternalServerError, body: serverErrorBody, want: false},
+		{name: "post bad gateway", method: http.MethodPost, statusCode: http.StatusBadGateway, body: nil, want: false},
+		{name: "delete rate limited", method: http.MethodDelete, statusCode: http.StatusTooManyRequests, body: nil, want: true},
+		{name: "delete service unavailable", method: http.MethodDelete, statusCode: http.StatusServiceUnavailable, body: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.method, tt.statusCode, tt.body); got != tt.want {
+				t.Errorf("isRetryable(%s, %d) = %v; want %v", tt.method, tt.statusCode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{
+		maxAttempts: 7,
+		baseDelay:   time.Second,
+		maxDelay:    32 * time.Second,
+	}
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		want       time.Duration
+	}{
+		{name: "first attempt", attempt: 1, want: time.Second},
+		{name: "second attempt", attempt: 2, want: 2 * time.Second},
+		{name: "fourth attempt", attempt: 4, want: 8 * time.Second},
+		{name: "capped at max delay", attempt: 7, want: 32 * time.Second},
+		{name: "overflowing shift", attempt: 80, want: 32 * time.Second},
+		{name: "retry after takes precedence", attempt: 5, retryAfter: "3", want: 3 * time.Second},
+		{name: "retry after capped at max delay", attempt: 1, retryAfter: "3600", want: 32 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.retryAfter != "" {
+				header.Set("Retry-After", tt.retryAfter)
+			}
+
+			if got := policy.delay(tt.attempt, header); got != tt.want {
+				t.Errorf("delay(%d) = %v; want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	policy := retryPolicy{
+		maxAttempts: 7,
+		baseDelay:   time.Second,
+		maxDelay:    32 * time.Second,
+		maxJitter:   time.Second,
+	}
+
+	for attempt := 1; attempt <= policy.maxAttempts; attempt++ {
+		base := min(policy.baseDelay<<(attempt-1), policy.maxDelay)
+		got := policy.delay(attempt, http.Header{})
+		if got < base || got >= base+policy.maxJitter {
+			t.Errorf("delay(%d) = %v; want within [%v, %v)", attempt, got, base, base+policy.maxJitter)
+		}
+	}
+}